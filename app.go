@@ -1,61 +1,553 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"html/template"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 // GameContent represents a complete set of content for one game session
 type GameContent struct {
+	ID           string // Assigned when the deck is cached
 	BusinessName string
 	Slogan       string
-	Image1       string
-	Image2       string
-	ClappingGif  string
+	Image1       string // Image store name
+	Image2       string // Image store name
+	ClappingGif  string // Giphy URL
+	Theme        string // The event theme it was generated under, if any
 	CreatedAt    time.Time
+	Approved     bool // Cleared by the host for review-required mode
 }
 
-// ContentCache holds pre-generated game content
+// ContentCache holds pre-generated game content, bounded by a deck count and
+// optionally by a byte budget
 type ContentCache struct {
 	items    []GameContent
 	mu       sync.RWMutex
 	maxSize  int
 	isLoaded bool
+
+	// Byte accounting, set up by SetByteBudget. sizes parallels items.
+	maxBytes   int64 // Zero means no byte budget
+	sizeOf     func(GameContent) int64
+	sizes      []int64
+	totalBytes int64
+	lastBytes  int64 // Size of the most recently added item
+
+	ttl   time.Duration // Zero means items never expire
+	stats CacheStats
+
+	// Only approved items can be popped while set
+	reviewRequired bool
+
+	// Items generated under any other theme are dropped
+	theme string
+
+	// Items mentioning anything on the glossary's do-not-mention list are dropped
+	doNotMention *Blocklist
+
+	// Snapshot location, set by EnablePersistence
+	metadataPath string
+
+	onChange func()
+
+	// Closed and replaced whenever an item becomes poppable to wake PopWait
+	// callers
+	available chan struct{}
+
+	// Recent Pop times, used to measure how fast content is consumed
+	popTimes []time.Time
+	lastPop  time.Time
+}
+
+// popHistory bounds how far back Pop times are remembered
+const popHistory = time.Hour
+
+// CacheStats counts how decks have left the cache since startup
+type CacheStats struct {
+	Consumed int // Served to a presenter
+	Expired  int // Dropped for being older than the TTL
+	Rethemed int // Dropped for being generated under a previous theme
+	Evicted  int // Dropped to stay within the count limit or byte budget
 }
 
 // NewContentCache creates a new content cache with specified max size
 func NewContentCache(maxSize int) *ContentCache {
 	return &ContentCache{
-		items:   make([]GameContent, 0, maxSize),
-		maxSize: maxSize,
+		items:     make([]GameContent, 0, maxSize),
+		maxSize:   maxSize,
+		available: make(chan struct{}),
 	}
 }
 
-// Pop removes and returns the first item from cache, or nil if empty
+// Pop removes and returns the first unexpired item from cache, or nil if
+// there is none. In review-required mode only approved items are returned.
 func (cc *ContentCache) Pop() *GameContent {
-	cc.mu.Lock()
-	defer cc.mu.Unlock()
+	now := time.Now()
 
-	if len(cc.items) == 0 {
+	cc.mu.Lock()
+	expired := cc.expireLocked(now)
+	i := cc.nextLocked()
+	if i < 0 {
+		if expired > 0 {
+			cc.persistLocked()
+		}
+		cc.mu.Unlock()
+		if expired > 0 {
+			cc.changed()
+		}
 		return nil
 	}
 
-	item := cc.items[0]
-	cc.items = cc.items[1:]
+	item := cc.items[i]
+	cc.removeAtLocked(i)
+	cc.stats.Consumed++
+	cc.recordPopLocked(now)
+	cc.persistLocked()
+	cc.mu.Unlock()
+
+	cc.changed()
 	return &item
 }
 
-// Push adds an item to the end of the cache, removing oldest if at capacity
+// PopWait removes and returns the first item from cache, waiting for one to
+// be pushed or approved if there is none. It gives up when ctx is done.
+func (cc *ContentCache) PopWait(ctx context.Context) (*GameContent, error) {
+	for {
+		if item := cc.Pop(); item != nil {
+			return item, nil
+		}
+
+		cc.mu.RLock()
+		available := cc.available
+		ready := cc.nextLocked() >= 0
+		cc.mu.RUnlock()
+		if ready {
+			continue // Pushed between the Pop and taking the channel
+		}
+
+		select {
+		case <-available:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Items returns a copy of the cached items, oldest first
+func (cc *ContentCache) Items() []GameContent {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return append([]GameContent(nil), cc.items...)
+}
+
+// Push adds an item to the end of the cache, removing the oldest items while
+// the cache is over its count limit or byte budget. Items generated under a
+// different theme or mentioning a do-not-mention entry are dropped.
 func (cc *ContentCache) Push(content GameContent) {
+	if content.ID == "" {
+		content.ID = newID()
+	}
+	size := cc.contentSize(content)
+
 	cc.mu.Lock()
-	defer cc.mu.Unlock()
+	if content.Theme != cc.theme {
+		cc.stats.Rethemed++
+		cc.mu.Unlock()
+		log.Printf("Dropped deck %q generated for a previous theme", content.BusinessName)
+		cc.changed()
+		return
+	}
+	if term, ok := cc.mentionLocked(content); ok {
+		cc.mu.Unlock()
+		log.Printf("Dropped deck %q: it mentions %q from the glossary's do-not-mention list", content.BusinessName, term)
+		return
+	}
+	cc.appendLocked(content, size)
+	cc.evictLocked()
+	cc.persistLocked()
+	cc.notifyLocked()
+	cc.mu.Unlock()
+
+	cc.changed()
+}
+
+// Remove drops the item with the given ID, reporting whether it was cached
+func (cc *ContentCache) Remove(id string) bool {
+	cc.mu.Lock()
+	i := cc.indexLocked(id)
+	if i < 0 {
+		cc.mu.Unlock()
+		return false
+	}
+	cc.removeAtLocked(i)
+	cc.persistLocked()
+	cc.mu.Unlock()
+
+	cc.changed()
+	return true
+}
+
+// Approve marks the item with the given ID as fit to be shown, reporting
+// whether it was cached
+func (cc *ContentCache) Approve(id string) bool {
+	cc.mu.Lock()
+	i := cc.indexLocked(id)
+	if i < 0 {
+		cc.mu.Unlock()
+		return false
+	}
+	cc.items[i].Approved = true
+	cc.persistLocked()
+	cc.notifyLocked()
+	cc.mu.Unlock()
+
+	cc.changed()
+	return true
+}
+
+// SetTheme drops items generated under any theme but the given one, now and
+// when they are pushed later, and returns how many were dropped
+func (cc *ContentCache) SetTheme(theme string) int {
+	cc.mu.Lock()
+	cc.theme = theme
+	dropped := cc.dropOtherThemesLocked()
+	if dropped > 0 {
+		cc.persistLocked()
+	}
+	cc.mu.Unlock()
+
+	cc.changed()
+	return dropped
+}
+
+// SetDoNotMention drops items mentioning any of the given terms, now and when
+// they are pushed later, and returns how many were dropped
+func (cc *ContentCache) SetDoNotMention(terms []string) int {
+	cc.mu.Lock()
+	cc.doNotMention = NewBlocklist(terms)
+	dropped := cc.dropMentionsLocked()
+	if dropped > 0 {
+		cc.persistLocked()
+	}
+	cc.mu.Unlock()
+
+	cc.changed()
+	return dropped
+}
+
+// Accepts reports whether an item was generated under the current theme and
+// mentions nothing on the do-not-mention list
+func (cc *ContentCache) Accepts(content GameContent) bool {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	_, mentioned := cc.mentionLocked(content)
+	return content.Theme == cc.theme && !mentioned
+}
+
+// mentionLocked returns the do-not-mention entry an item's text mentions, if
+// any. Assumes cc.mu is already locked.
+func (cc *ContentCache) mentionLocked(content GameContent) (string, bool) {
+	return cc.doNotMention.Match(content.BusinessName + " - " + content.Slogan)
+}
+
+// dropMentionsLocked drops items mentioning a do-not-mention entry and
+// returns how many were dropped. Assumes cc.mu is already locked.
+func (cc *ContentCache) dropMentionsLocked() int {
+	dropped := 0
+	for i := len(cc.items) - 1; i >= 0; i-- {
+		if _, ok := cc.mentionLocked(cc.items[i]); ok {
+			cc.removeAtLocked(i)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Theme returns the theme decks must have been generated under
+func (cc *ContentCache) Theme() string {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.theme
+}
 
-	if len(cc.items) >= cc.maxSize {
-		cc.items = cc.items[1:] // Remove oldest
+// dropOtherThemesLocked drops items generated under a different theme and
+// returns how many were dropped. Assumes cc.mu is already locked.
+func (cc *ContentCache) dropOtherThemesLocked() int {
+	dropped := 0
+	for i := len(cc.items) - 1; i >= 0; i-- {
+		if cc.items[i].Theme != cc.theme {
+			cc.removeAtLocked(i)
+			dropped++
+		}
+	}
+	cc.stats.Rethemed += dropped
+	return dropped
+}
+
+// SetReviewRequired controls whether only approved items can be popped
+func (cc *ContentCache) SetReviewRequired(required bool) {
+	cc.mu.Lock()
+	cc.reviewRequired = required
+	if !required {
+		cc.notifyLocked() // Unapproved items are poppable again
+	}
+	cc.mu.Unlock()
+
+	cc.changed()
+}
+
+// ReviewRequired returns whether only approved items can be popped
+func (cc *ContentCache) ReviewRequired() bool {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.reviewRequired
+}
+
+// nextLocked returns the index of the item Pop would return, or -1.
+// Assumes cc.mu is already locked.
+func (cc *ContentCache) nextLocked() int {
+	for i, item := range cc.items {
+		if item.Approved || !cc.reviewRequired {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexLocked returns the index of the item with the given ID, or -1.
+// Assumes cc.mu is already locked.
+func (cc *ContentCache) indexLocked(id string) int {
+	for i, item := range cc.items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// notifyLocked wakes PopWait callers. Assumes cc.mu is already locked.
+func (cc *ContentCache) notifyLocked() {
+	close(cc.available)
+	cc.available = make(chan struct{})
+}
+
+// SetByteBudget limits the cache to maxBytes in total, as measured by sizeOf,
+// in addition to its count limit. A maxBytes of zero removes the budget.
+func (cc *ContentCache) SetByteBudget(maxBytes int64, sizeOf func(GameContent) int64) {
+	cc.mu.Lock()
+	cc.maxBytes = maxBytes
+	cc.sizeOf = sizeOf
+	items := cc.items
+	cc.items, cc.sizes, cc.totalBytes = nil, nil, 0
+	for _, item := range items {
+		cc.appendLocked(item, sizeOf(item))
 	}
+	cc.evictLocked()
+	cc.mu.Unlock()
 
+	cc.changed()
+}
+
+// contentSize measures an item for the byte budget
+func (cc *ContentCache) contentSize(content GameContent) int64 {
+	cc.mu.RLock()
+	sizeOf := cc.sizeOf
+	cc.mu.RUnlock()
+	if sizeOf == nil {
+		return gameContentTextSize(content)
+	}
+	return sizeOf(content)
+}
+
+// gameContentTextSize returns the bytes taken by a deck's text fields
+func gameContentTextSize(content GameContent) int64 {
+	return int64(len(content.BusinessName) + len(content.Slogan) + len(content.Image1) + len(content.Image2) + len(content.ClappingGif))
+}
+
+// appendLocked adds an item of the given size. Assumes cc.mu is already locked.
+func (cc *ContentCache) appendLocked(content GameContent, size int64) {
 	cc.items = append(cc.items, content)
+	cc.sizes = append(cc.sizes, size)
+	cc.totalBytes += size
+	cc.lastBytes = size
+}
+
+// removeAtLocked drops the item at index i. Assumes cc.mu is already locked.
+func (cc *ContentCache) removeAtLocked(i int) {
+	cc.totalBytes -= cc.sizes[i]
+	cc.items = slices.Delete(cc.items, i, i+1)
+	cc.sizes = slices.Delete(cc.sizes, i, i+1)
+}
+
+// evictLocked drops the oldest items until the cache is within its limits,
+// unapproved ones first so the host's approvals are not thrown away. The
+// cache is never emptied, even if one item alone exceeds the byte budget.
+// Assumes cc.mu is already locked.
+func (cc *ContentCache) evictLocked() {
+	for len(cc.items) > cc.maxSize || (cc.maxBytes > 0 && cc.totalBytes > cc.maxBytes && len(cc.items) > 1) {
+		i := slices.IndexFunc(cc.items, func(item GameContent) bool { return !item.Approved })
+		if i < 0 {
+			i = 0
+		}
+		cc.removeAtLocked(i)
+		cc.stats.Evicted++
+	}
+}
+
+// SetTTL makes items expire once they are older than ttl, judged by their
+// CreatedAt. Items without a CreatedAt never expire. Zero disables expiry.
+func (cc *ContentCache) SetTTL(ttl time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.ttl = ttl
+}
+
+// Sweep drops expired items and returns how many were dropped
+func (cc *ContentCache) Sweep(now time.Time) int {
+	cc.mu.Lock()
+	expired := cc.expireLocked(now)
+	if expired > 0 {
+		cc.persistLocked()
+	}
+	cc.mu.Unlock()
+
+	if expired > 0 {
+		cc.changed()
+	}
+	return expired
+}
+
+// expireLocked drops items older than the TTL and returns how many were
+// dropped. Assumes cc.mu is already locked.
+func (cc *ContentCache) expireLocked(now time.Time) int {
+	if cc.ttl <= 0 {
+		return 0
+	}
+
+	cutoff := now.Add(-cc.ttl)
+	kept, keptSizes := cc.items[:0:0], cc.sizes[:0:0]
+	for i, item := range cc.items {
+		if !item.CreatedAt.IsZero() && item.CreatedAt.Before(cutoff) {
+			cc.totalBytes -= cc.sizes[i]
+			continue
+		}
+		kept = append(kept, item)
+		keptSizes = append(keptSizes, cc.sizes[i])
+	}
+
+	expired := len(cc.items) - len(kept)
+	cc.items, cc.sizes = kept, keptSizes
+	cc.stats.Expired += expired
+	return expired
+}
+
+// Stats returns how many decks have been consumed, expired, rethemed and evicted
+func (cc *ContentCache) Stats() CacheStats {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.stats
+}
+
+// SizeBytes returns the total size of the cached items
+func (cc *ContentCache) SizeBytes() int64 {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.totalBytes
+}
+
+// MaxBytes returns the byte budget, or zero if there is none
+func (cc *ContentCache) MaxBytes() int64 {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.maxBytes
+}
+
+// Capacity returns how many items the cache can hold: its count limit, or
+// fewer if the byte budget only fits fewer items of the typical size
+func (cc *ContentCache) Capacity() int {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	typical := cc.lastBytes
+	if len(cc.items) > 0 {
+		typical = cc.totalBytes / int64(len(cc.items))
+	}
+	if cc.maxBytes <= 0 || typical <= 0 {
+		return cc.maxSize
+	}
+	return min(cc.maxSize, max(int(cc.maxBytes/typical), 1))
+}
+
+// recordPopLocked remembers a Pop for consumption tracking.
+// Assumes cc.mu is already locked.
+func (cc *ContentCache) recordPopLocked(now time.Time) {
+	cc.lastPop = now
+	cc.popTimes = append(cc.popTimes, now)
+
+	cutoff := now.Add(-popHistory)
+	i := 0
+	for i < len(cc.popTimes) && cc.popTimes[i].Before(cutoff) {
+		i++
+	}
+	cc.popTimes = cc.popTimes[i:]
+}
+
+// RecordConsumed counts a deck handed straight to a presenter without passing
+// through the cache, so consumption is still measured while the cache is empty
+func (cc *ContentCache) RecordConsumed(now time.Time) {
+	cc.mu.Lock()
+	cc.stats.Consumed++
+	cc.recordPopLocked(now)
+	cc.mu.Unlock()
+
+	cc.changed()
+}
+
+// PopsSince returns how many items were popped after the given time, up to
+// the last hour
+func (cc *ContentCache) PopsSince(t time.Time) int {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	count := 0
+	for _, popped := range cc.popTimes {
+		if popped.After(t) {
+			count++
+		}
+	}
+	return count
+}
+
+// LastPop returns when an item was last popped, or the zero time if never
+func (cc *ContentCache) LastPop() time.Time {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.lastPop
+}
+
+// OnChange registers a function called after every change to the cache
+func (cc *ContentCache) OnChange(fn func()) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.onChange = fn
+}
+
+// changed calls the change hook. It must be called without cc.mu held so the
+// hook can read the cache.
+func (cc *ContentCache) changed() {
+	cc.mu.RLock()
+	fn := cc.onChange
+	cc.mu.RUnlock()
+	if fn != nil {
+		fn()
+	}
 }
 
 // Size returns the current number of cached items
@@ -68,8 +560,10 @@ func (cc *ContentCache) Size() int {
 // SetLoaded marks the cache as having been initially loaded
 func (cc *ContentCache) SetLoaded() {
 	cc.mu.Lock()
-	defer cc.mu.Unlock()
 	cc.isLoaded = true
+	cc.mu.Unlock()
+
+	cc.changed()
 }
 
 // IsLoaded returns whether the cache has been initially loaded
@@ -79,23 +573,80 @@ func (cc *ContentCache) IsLoaded() bool {
 	return cc.isLoaded
 }
 
+// parseByteSize parses a size such as "52428800", "512K", "50MB" or "1GiB".
+// Suffixes are binary multiples.
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			s = strings.TrimSpace(s[:n-1])
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", value)
+	}
+	return n * multiplier, nil
+}
+
+// formatBytes renders a byte count for humans
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
 // App holds the application dependencies and state
 type App struct {
-	participants     []string
-	participantsMu   sync.Mutex
-	templates        *template.Template
-	usedGifs         map[string]bool
-	usedGifsMu       sync.Mutex
-	giphyAPIKey      string
-	giphyCache       []string
-	giphyCacheMu     sync.Mutex
-	giphyCacheExpiry time.Time
-	googleAPIKey     string
-	generator        Generator
-	contentCache     *ContentCache
-	preloadStop      chan struct{}
-	preloadRunning   bool
-	preloadMu        sync.Mutex
+	participants       []string
+	participantsMu     sync.Mutex
+	stateStore         StateStore
+	templates          *template.Template
+	usedGifs           map[string]bool
+	usedGifsMu         sync.Mutex
+	giphyAPIKey        string
+	giphyCache         []string
+	giphyCacheMu       sync.Mutex
+	giphyCacheExpiry   time.Time
+	googleAPIKey       string
+	generator          Generator
+	prompts            *PromptStore
+	theme              Theme // Event theme, persisted with the participants
+	themeMu            sync.RWMutex
+	glossary           Glossary // Company glossary, persisted with the participants
+	glossaryMu         sync.RWMutex
+	coherentDeck       bool
+	contentCache       *ContentCache
+	images             ImageStore
+	sessions           *SessionManager
+	events             *EventBroker
+	auth               *Authenticator
+	preloadStop        chan struct{}
+	preloadRunning     bool
+	preloadMu          sync.Mutex
+	preloadWorkers     int           // Maximum concurrent preload generations
+	preloadIdleTimeout time.Duration // Consumption gap after which preloading trickles
+	preloadPlan        PreloadPlan
+	cacheWaitTimeout   time.Duration        // How long a presenter waits for the initial fill
+	scheduler          *GenerationScheduler // Runs every deck generation, live requests first
 	// We can add clients for external services here later
 }
 
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContentCacheWarmStart(t *testing.T) {
+	dir := t.TempDir()
+	metadataPath := filepath.Join(dir, "cache.json")
+	images := NewFileImageStore(filepath.Join(dir, "images"))
+
+	put := func(data string) string {
+		name, err := images.Put([]byte(data), "image/png")
+		if err != nil {
+			t.Fatalf("failed to store image: %v", err)
+		}
+		return name
+	}
+	first, second, shared := put("first"), put("second"), put("shared")
+
+	cache := NewContentCache(5)
+	if err := cache.EnablePersistence(metadataPath, images); err != nil {
+		t.Fatalf("failed to enable persistence: %v", err)
+	}
+	cache.Push(GameContent{BusinessName: "First", Slogan: "One", Image1: first, Image2: shared, CreatedAt: time.Now()})
+	cache.Push(GameContent{BusinessName: "Second", Slogan: "Two", Image1: second, Image2: shared, CreatedAt: time.Now()})
+	cache.Push(GameContent{BusinessName: "Broken", Slogan: "Three", Image1: second, Image2: imageName([]byte("gone"), "image/png"), CreatedAt: time.Now()})
+	cache.Pop()
+
+	restored := NewContentCache(5)
+	if err := restored.EnablePersistence(metadataPath, images); err != nil {
+		t.Fatalf("failed to restore cache: %v", err)
+	}
+	if size := restored.Size(); size != 1 {
+		t.Fatalf("expected 1 restored item, skipping the one with a missing image, got %d", size)
+	}
+
+	item := restored.Pop()
+	if item.BusinessName != "Second" || item.Slogan != "Two" {
+		t.Errorf("unexpected restored item: %+v", item)
+	}
+	if item.Image1 != second || item.Image2 != shared {
+		t.Errorf("restored images do not match: %q, %q", item.Image1, item.Image2)
+	}
+}
+
+func TestContentCachePopWait(t *testing.T) {
+	cache := NewContentCache(5)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cache.Push(GameContent{BusinessName: "Late Deck"})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	item, err := cache.PopWait(ctx)
+	if err != nil {
+		t.Fatalf("expected the pushed item, got %v", err)
+	}
+	if item.BusinessName != "Late Deck" {
+		t.Errorf("unexpected item %+v", item)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := cache.PopWait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to expire on an empty cache, got %v", err)
+	}
+}
+
+func TestContentCacheReviewRequired(t *testing.T) {
+	cache := NewContentCache(5)
+	cache.SetReviewRequired(true)
+	cache.Push(GameContent{BusinessName: "Unreviewed"})
+	cache.Push(GameContent{BusinessName: "Reviewed"})
+
+	if item := cache.Pop(); item != nil {
+		t.Fatalf("expected nothing before approval, got %+v", item)
+	}
+
+	items := cache.Items()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cache.Approve(items[1].ID)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	item, err := cache.PopWait(ctx)
+	if err != nil || item.BusinessName != "Reviewed" {
+		t.Fatalf("expected the approved deck, got %+v (%v)", item, err)
+	}
+
+	if !cache.Remove(items[0].ID) || cache.Size() != 0 {
+		t.Error("expected the unreviewed deck to be removed")
+	}
+	if cache.Remove(items[0].ID) {
+		t.Error("expected removing a missing deck to fail")
+	}
+
+	// A full cache evicts unapproved decks before approved ones
+	small := NewContentCache(2)
+	small.Push(GameContent{BusinessName: "Approved"})
+	small.Approve(small.Items()[0].ID)
+	small.Push(GameContent{BusinessName: "Unreviewed"})
+	small.Push(GameContent{BusinessName: "Newest"})
+	if items := small.Items(); len(items) != 2 || items[0].BusinessName != "Approved" || items[1].BusinessName != "Newest" {
+		t.Errorf("expected the unreviewed deck to be evicted, got %+v", items)
+	}
+}
+
+func TestContentCacheByteBudget(t *testing.T) {
+	cache := NewContentCache(10)
+	cache.SetByteBudget(250, func(content GameContent) int64 {
+		return int64(len(content.Slogan))
+	})
+	deck := func(name string, size int) GameContent {
+		return GameContent{BusinessName: name, Slogan: string(make([]byte, size))}
+	}
+
+	cache.Push(deck("First", 100))
+	cache.Push(deck("Second", 100))
+	if size, bytes := cache.Size(), cache.SizeBytes(); size != 2 || bytes != 200 {
+		t.Fatalf("expected 2 decks in 200 bytes, got %d in %d", size, bytes)
+	}
+	if capacity := cache.Capacity(); capacity != 2 {
+		t.Errorf("expected the budget to fit 2 decks of 100 bytes, got %d", capacity)
+	}
+
+	// Over budget: the oldest deck goes even though the count limit allows 10
+	cache.Push(deck("Third", 100))
+	if size, bytes := cache.Size(), cache.SizeBytes(); size != 2 || bytes != 200 {
+		t.Fatalf("expected eviction down to 2 decks in 200 bytes, got %d in %d", size, bytes)
+	}
+	if first := cache.Pop(); first.BusinessName != "Second" {
+		t.Errorf("expected the oldest deck to be evicted, got %q first", first.BusinessName)
+	}
+	if bytes := cache.SizeBytes(); bytes != 100 {
+		t.Errorf("expected Pop to release its bytes, got %d", bytes)
+	}
+
+	// A deck bigger than the whole budget is still kept on its own
+	cache.Push(deck("Huge", 400))
+	if size := cache.Size(); size != 1 {
+		t.Errorf("expected only the oversized deck to remain, got %d decks", size)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":  1024,
+		"512K":  512 << 10,
+		"50MB":  50 << 20,
+		"1GiB":  1 << 30,
+		" 2 mb": 2 << 20,
+	}
+	for input, want := range tests {
+		if got, err := parseByteSize(input); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "lots", "-5M"} {
+		if _, err := parseByteSize(input); err == nil {
+			t.Errorf("parseByteSize(%q) should fail", input)
+		}
+	}
+}
+
+func TestContentCacheExpiry(t *testing.T) {
+	cache := NewContentCache(10)
+	cache.SetTTL(time.Hour)
+	now := time.Now()
+
+	cache.Push(GameContent{BusinessName: "Ancient", CreatedAt: now.Add(-2 * time.Hour)})
+	cache.Push(GameContent{BusinessName: "Fresh", CreatedAt: now.Add(-time.Minute)})
+	cache.Push(GameContent{BusinessName: "Aging", CreatedAt: now.Add(-50 * time.Minute)})
+
+	// Pop never serves a stale deck
+	if item := cache.Pop(); item.BusinessName != "Fresh" {
+		t.Errorf("expected the stale deck to be skipped, got %q", item.BusinessName)
+	}
+
+	if expired := cache.Sweep(now.Add(15 * time.Minute)); expired != 1 {
+		t.Errorf("expected the aging deck to expire, swept %d", expired)
+	}
+	if size := cache.Size(); size != 0 {
+		t.Errorf("expected an empty cache, got %d", size)
+	}
+
+	if stats := cache.Stats(); stats != (CacheStats{Consumed: 1, Expired: 2}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestContentCacheRestoreExpiresBeforeEvicting(t *testing.T) {
+	dir := t.TempDir()
+	metadataPath := filepath.Join(dir, "cache.json")
+	images := NewFileImageStore(filepath.Join(dir, "images"))
+	image, err := images.Put([]byte("image"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewContentCache(5)
+	if err := cache.EnablePersistence(metadataPath, images); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	cache.Push(GameContent{BusinessName: "Fresh", Image1: image, Image2: image, CreatedAt: now})
+	cache.Push(GameContent{BusinessName: "Stale", Image1: image, Image2: image, CreatedAt: now.Add(-2 * time.Hour)})
+
+	// Only one deck fits, and the stale one must not take its place
+	restored := NewContentCache(1)
+	restored.SetTTL(time.Hour)
+	if err := restored.EnablePersistence(metadataPath, images); err != nil {
+		t.Fatal(err)
+	}
+	if item := restored.Pop(); item == nil || item.BusinessName != "Fresh" {
+		t.Errorf("expected the fresh deck to be kept, got %+v", item)
+	}
+	if stats := restored.Stats(); stats != (CacheStats{Consumed: 1, Expired: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestContentCacheRecoversFromCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	metadataPath := filepath.Join(dir, "cache.json")
+	images := NewFileImageStore(filepath.Join(dir, "images"))
+	if err := os.WriteFile(metadataPath, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewContentCache(5)
+	if err := cache.EnablePersistence(metadataPath, images); err == nil {
+		t.Fatal("expected the corrupt snapshot to be reported")
+	}
+	if _, err := os.Stat(metadataPath + ".corrupt"); err != nil {
+		t.Errorf("expected the corrupt snapshot to be kept aside: %v", err)
+	}
+
+	// The next change still writes a snapshot the following start can load
+	image, err := images.Put([]byte("image"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Push(GameContent{BusinessName: "After Recovery", Image1: image, Image2: image, CreatedAt: time.Now()})
+
+	restored := NewContentCache(5)
+	if err := restored.EnablePersistence(metadataPath, images); err != nil {
+		t.Fatalf("failed to restore cache: %v", err)
+	}
+	if item := restored.Pop(); item == nil || item.BusinessName != "After Recovery" {
+		t.Errorf("expected the deck pushed after recovery, got %+v", item)
+	}
+}
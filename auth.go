@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	adminCookieName = "ignite_admin"
+	adminSessionTTL = 12 * time.Hour
+)
+
+// Authenticator checks admin credentials and tracks logged-in admin sessions
+type Authenticator struct {
+	password    []byte            // Shared admin password, if configured
+	credentials map[string][]byte // Username to bcrypt hash, if loaded from a file
+
+	mu       sync.Mutex
+	sessions map[string]time.Time // Session token to expiry
+}
+
+// NewPasswordAuthenticator creates an authenticator for a single shared password
+func NewPasswordAuthenticator(password string) *Authenticator {
+	return &Authenticator{
+		password: []byte(password),
+		sessions: make(map[string]time.Time),
+	}
+}
+
+// LoadCredentialsFile creates an authenticator from a file of
+// "username:bcrypt-hash" lines. Blank lines and lines starting with # are ignored.
+func LoadCredentialsFile(path string) (*Authenticator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credentials file: %w", err)
+	}
+	defer file.Close()
+
+	credentials := make(map[string][]byte)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, hash, ok := strings.Cut(line, ":")
+		if !ok || username == "" {
+			return nil, fmt.Errorf("%s:%d: expected username:bcrypt-hash", path, lineNo)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid bcrypt hash for %s: %w", path, lineNo, username, err)
+		}
+		credentials[username] = []byte(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("credentials file %s has no entries", path)
+	}
+
+	return &Authenticator{
+		credentials: credentials,
+		sessions:    make(map[string]time.Time),
+	}, nil
+}
+
+// UsesUsernames reports whether logging in requires a username
+func (a *Authenticator) UsesUsernames() bool {
+	return a.credentials != nil
+}
+
+// Check reports whether the username and password are valid admin credentials
+func (a *Authenticator) Check(username, password string) bool {
+	if a.credentials != nil {
+		hash, ok := a.credentials[username]
+		if !ok {
+			return false
+		}
+		return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+	}
+
+	// Compare digests so the comparison time does not leak the password length
+	want := sha256.Sum256(a.password)
+	got := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(want[:], got[:]) == 1
+}
+
+// StartSession creates a new admin session and returns its token
+func (a *Authenticator) StartSession() string {
+	token := newID()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sessions[token] = time.Now().Add(adminSessionTTL)
+	return token
+}
+
+// ValidSession reports whether token belongs to an unexpired admin session
+func (a *Authenticator) ValidSession(token string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for t, expiry := range a.sessions {
+		if now.After(expiry) {
+			delete(a.sessions, t)
+		}
+	}
+	_, ok := a.sessions[token]
+	return ok
+}
+
+// EndSession logs the admin session out
+func (a *Authenticator) EndSession(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, token)
+}
+
+// isAdmin reports whether the request carries a valid admin session cookie
+func (app *App) isAdmin(r *http.Request) bool {
+	if app.auth == nil {
+		return true
+	}
+	cookie, err := r.Cookie(adminCookieName)
+	if err != nil {
+		return false
+	}
+	return app.auth.ValidSession(cookie.Value)
+}
+
+// requireAdmin wraps a handler so only logged-in admins can reach it
+func (app *App) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.isAdmin(r) {
+			next(w, r)
+			return
+		}
+
+		// Only GETs can be replayed after login; anything else starts from the home page
+		returnTo := "/"
+		if r.Method == http.MethodGet {
+			returnTo = r.URL.RequestURI()
+		}
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(returnTo), http.StatusSeeOther)
+	}
+}
+
+func (app *App) loginHandler(w http.ResponseWriter, r *http.Request) {
+	returnTo := r.FormValue("next")
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		returnTo = "/admin"
+	}
+
+	data := struct {
+		Next          string
+		Error         string
+		UsesUsernames bool
+		CSRFToken     string
+	}{
+		Next:          returnTo,
+		UsesUsernames: app.auth.UsesUsernames(),
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if app.auth.Check(r.FormValue("username"), r.FormValue("password")) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     adminCookieName,
+				Value:    app.auth.StartSession(),
+				Path:     "/",
+				MaxAge:   int(adminSessionTTL.Seconds()),
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+			http.Redirect(w, r, returnTo, http.StatusSeeOther)
+			return
+		}
+		log.Printf("Failed admin login attempt from %s", r.RemoteAddr)
+		data.Error = "Invalid credentials"
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data.CSRFToken = csrfToken(w, r)
+	if data.Error != "" {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	app.templates.ExecuteTemplate(w, "login.html", data)
+}
+
+func (app *App) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(adminCookieName); err == nil {
+		app.auth.EndSession(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
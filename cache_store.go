@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// cacheSnapshot is the on-disk metadata for the content cache. Images live in
+// the image store and are referenced by name.
+type cacheSnapshot struct {
+	Items []cachedContent `json:"items"`
+}
+
+type cachedContent struct {
+	ID           string    `json:"id"`
+	BusinessName string    `json:"businessName"`
+	Slogan       string    `json:"slogan"`
+	Image1       string    `json:"image1"`
+	Image2       string    `json:"image2"`
+	ClappingGif  string    `json:"clappingGif"`
+	Theme        string    `json:"theme,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Approved     bool      `json:"approved,omitempty"`
+}
+
+// EnablePersistence reloads any snapshot previously written to metadataPath
+// and snapshots the cache there on every change. Items whose images are
+// missing from images are dropped. A snapshot that can't be loaded is moved
+// aside and reported, but persistence is still enabled so the next change
+// writes a good one.
+func (cc *ContentCache) EnablePersistence(metadataPath string, images ImageStore) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.metadataPath = metadataPath
+
+	items, err := loadCacheSnapshot(metadataPath, images)
+	if err != nil {
+		if renameErr := os.Rename(metadataPath, metadataPath+".corrupt"); renameErr != nil {
+			log.Printf("Failed to move the unreadable cache snapshot aside: %v", renameErr)
+		}
+		return err
+	}
+
+	for _, item := range items {
+		size := gameContentTextSize(item)
+		if cc.sizeOf != nil {
+			size = cc.sizeOf(item)
+		}
+		cc.appendLocked(item, size)
+	}
+	// Drop decks that can never be served before evicting for space, so they
+	// don't push out fresh ones
+	if expired := cc.expireLocked(time.Now()); expired > 0 {
+		log.Printf("Dropped %d restored decks older than %v", expired, cc.ttl)
+	}
+	if dropped := cc.dropOtherThemesLocked(); dropped > 0 {
+		log.Printf("Dropped %d restored decks generated for a previous theme", dropped)
+	}
+	if dropped := cc.dropMentionsLocked(); dropped > 0 {
+		log.Printf("Dropped %d restored decks mentioning the glossary's do-not-mention list", dropped)
+	}
+	cc.evictLocked()
+	return nil
+}
+
+// persistLocked writes the current cache contents to disk.
+// Assumes cc.mu is already locked.
+func (cc *ContentCache) persistLocked() {
+	if cc.metadataPath == "" {
+		return
+	}
+	if err := saveCacheSnapshot(cc.metadataPath, cc.items); err != nil {
+		log.Printf("Failed to persist content cache: %v", err)
+	}
+}
+
+func saveCacheSnapshot(metadataPath string, items []GameContent) error {
+	snapshot := cacheSnapshot{Items: make([]cachedContent, 0, len(items))}
+	for _, item := range items {
+		snapshot.Items = append(snapshot.Items, cachedContent{
+			ID:           item.ID,
+			BusinessName: item.BusinessName,
+			Slogan:       item.Slogan,
+			Image1:       item.Image1,
+			Image2:       item.Image2,
+			ClappingGif:  item.ClappingGif,
+			Theme:        item.Theme,
+			CreatedAt:    item.CreatedAt,
+			Approved:     item.Approved,
+		})
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache snapshot: %w", err)
+	}
+	return writeFileAtomic(metadataPath, data)
+}
+
+func loadCacheSnapshot(metadataPath string, images ImageStore) ([]GameContent, error) {
+	data, err := os.ReadFile(metadataPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache snapshot: %w", err)
+	}
+
+	var snapshot cacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode cache snapshot %s: %w", metadataPath, err)
+	}
+
+	items := make([]GameContent, 0, len(snapshot.Items))
+	for _, cached := range snapshot.Items {
+		if !images.Has(cached.Image1) || !images.Has(cached.Image2) {
+			log.Printf("Skipping cached content %q: image missing", cached.BusinessName)
+			continue
+		}
+
+		id := cached.ID
+		if id == "" {
+			id = newID() // Written before decks had IDs
+		}
+		items = append(items, GameContent{
+			ID:           id,
+			BusinessName: cached.BusinessName,
+			Slogan:       cached.Slogan,
+			Image1:       cached.Image1,
+			Image2:       cached.Image2,
+			ClappingGif:  cached.ClappingGif,
+			Theme:        cached.Theme,
+			CreatedAt:    cached.CreatedAt,
+			Approved:     cached.Approved,
+		})
+	}
+	return items, nil
+}
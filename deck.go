@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// DeckBuild is a deck whose parts are filled in as they are generated, so a
+// game can start on the pitch while the images are still rendering
+type DeckBuild struct {
+	mu      sync.Mutex
+	state   DeckState
+	changed chan struct{} // Closed and replaced on every change
+}
+
+// DeckState is a snapshot of a DeckBuild
+type DeckState struct {
+	Content GameContent // Parts generated so far
+	Done    bool        // Whether generation is over
+	Err     error       // Why generation failed, if it did
+}
+
+// HasPitch reports whether the business name and slogan are ready
+func (s DeckState) HasPitch() bool {
+	return s.Content.BusinessName != ""
+}
+
+func newDeckBuild() *DeckBuild {
+	return &DeckBuild{changed: make(chan struct{})}
+}
+
+// completedDeck wraps content that is already fully generated
+func completedDeck(content GameContent) *DeckBuild {
+	deck := newDeckBuild()
+	deck.state = DeckState{Content: content, Done: true}
+	return deck
+}
+
+// State returns the current snapshot and a channel closed on the next change
+func (d *DeckBuild) State() (DeckState, <-chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.state, d.changed
+}
+
+// Wait blocks until generation is over and returns the final snapshot
+func (d *DeckBuild) Wait(ctx context.Context) (DeckState, error) {
+	for {
+		state, changed := d.State()
+		if state.Done {
+			return state, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return state, ctx.Err()
+		}
+	}
+}
+
+// update records the parts generated so far
+func (d *DeckBuild) update(content GameContent) {
+	d.set(func(state *DeckState) {
+		state.Content = content
+	})
+}
+
+// finish records the outcome of generation
+func (d *DeckBuild) finish(content *GameContent, err error) {
+	d.set(func(state *DeckState) {
+		if content != nil {
+			state.Content = *content
+		}
+		state.Done = true
+		state.Err = err
+	})
+}
+
+func (d *DeckBuild) set(fn func(state *DeckState)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(&d.state)
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event is a message broadcast to every page subscribed to the event stream
+type Event struct {
+	Type string
+	Data any
+}
+
+// EventBroker fans events out to Server-Sent Event subscribers
+type EventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewEventBroker creates a broker with no subscribers
+func NewEventBroker() *EventBroker {
+	return &EventBroker{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber. The returned function must be called
+// to unsubscribe once the subscriber goes away.
+func (b *EventBroker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, ch)
+	}
+}
+
+// Publish sends an event to all subscribers without blocking. Subscribers
+// that have fallen behind miss the event; every event carries full state, so
+// the next one brings them back up to date.
+func (b *EventBroker) Publish(eventType string, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event := Event{Type: eventType, Data: data}
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// queueEvent is the payload of "queue" events
+type queueEvent struct {
+	Participants []string `json:"participants"`
+	Next         string   `json:"next"`
+}
+
+// cacheEvent is the payload of "cache" events
+type cacheEvent struct {
+	Size     int   `json:"size"`
+	MaxSize  int   `json:"maxSize"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"maxBytes"` // Zero when there is no byte budget
+	Loaded   bool  `json:"loaded"`
+	Consumed int   `json:"consumed"`
+	Expired  int   `json:"expired"`
+	Rethemed int   `json:"rethemed"`
+	Evicted  int   `json:"evicted"`
+}
+
+// gameEvent is the payload of "game" events
+type gameEvent struct {
+	Action          string        `json:"action"`
+	SessionID       string        `json:"sessionId,omitempty"`
+	ParticipantName string        `json:"participantName,omitempty"`
+	Games           []gameSummary `json:"games"`
+}
+
+type gameSummary struct {
+	SessionID       string         `json:"sessionId"`
+	ParticipantName string         `json:"participantName"`
+	Timer           GameTimerState `json:"timer"`
+}
+
+// progressEvent is the payload of "progress" events, sent while a presenter
+// waits for their deck
+type progressEvent struct {
+	ParticipantName string `json:"participantName"`
+	Stage           string `json:"stage"`
+	Message         string `json:"message"`
+}
+
+// publish broadcasts an event if the app has an event broker
+func (app *App) publish(eventType string, data any) {
+	if app.events == nil {
+		return
+	}
+	app.events.Publish(eventType, data)
+}
+
+// reportProgress tells the presenter's page what their deck is waiting on
+func (app *App) reportProgress(participantName, stage, message string) {
+	app.publish("progress", progressEvent{
+		ParticipantName: participantName,
+		Stage:           stage,
+		Message:         message,
+	})
+}
+
+// queueEventLocked builds a "queue" event payload.
+// Assumes participantsMu is already locked.
+func (app *App) queueEventLocked() queueEvent {
+	event := queueEvent{Participants: append([]string{}, app.participants...)}
+	if len(app.participants) > 0 {
+		event.Next = app.participants[0]
+	}
+	return event
+}
+
+// publishQueueLocked broadcasts the participant queue.
+// Assumes participantsMu is already locked.
+func (app *App) publishQueueLocked() {
+	app.publish("queue", app.queueEventLocked())
+}
+
+func (app *App) cacheEvent() cacheEvent {
+	stats := app.contentCache.Stats()
+	return cacheEvent{
+		Size:     app.contentCache.Size(),
+		MaxSize:  app.contentCache.maxSize,
+		Bytes:    app.contentCache.SizeBytes(),
+		MaxBytes: app.contentCache.MaxBytes(),
+		Loaded:   app.contentCache.IsLoaded(),
+		Consumed: stats.Consumed,
+		Expired:  stats.Expired,
+		Rethemed: stats.Rethemed,
+		Evicted:  stats.Evicted,
+	}
+}
+
+func (app *App) gameEvent(action string, session GameSession) gameEvent {
+	event := gameEvent{
+		Action:          action,
+		SessionID:       session.ID,
+		ParticipantName: session.ParticipantName,
+		Games:           []gameSummary{},
+	}
+	now := time.Now()
+	for _, s := range app.sessions.List() {
+		event.Games = append(event.Games, gameSummary{
+			SessionID:       s.ID,
+			ParticipantName: s.ParticipantName,
+			Timer:           s.TimerState(now),
+		})
+	}
+	return event
+}
+
+// eventsHandler streams queue, cache, game and theme events to the browser
+func (app *App) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := app.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// Send the current state first so the page is correct without waiting for a change
+	app.participantsMu.Lock()
+	queue := app.queueEventLocked()
+	app.participantsMu.Unlock()
+	initial := []Event{
+		{Type: "queue", Data: queue},
+		{Type: "cache", Data: app.cacheEvent()},
+		{Type: "game", Data: app.gameEvent("snapshot", GameSession{})},
+		{Type: "theme", Data: app.currentTheme()},
+	}
+	for _, event := range initial {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			// Comment lines keep proxies from closing an idle connection
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", event.Type, err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// defaultGlossaryProbability is how often a request references a glossary
+// term until the host changes it
+const defaultGlossaryProbability = 0.5
+
+// Glossary is the company's own vocabulary: products and in-jokes decks can
+// reference, and topics they must never touch
+type Glossary struct {
+	Terms        []GlossaryTerm `json:"terms,omitempty"`
+	DoNotMention []string       `json:"doNotMention,omitempty"`
+	Probability  float64        `json:"probability"` // Chance each request references a term, 0 to 1
+}
+
+// GlossaryTerm is one product name or in-joke and what it means
+type GlossaryTerm struct {
+	Term        string `json:"term"`
+	Description string `json:"description,omitempty"`
+}
+
+// IsZero reports whether the glossary has nothing in it
+func (g Glossary) IsZero() bool {
+	return len(g.Terms) == 0 && len(g.DoNotMention) == 0
+}
+
+// parseGlossary builds a glossary from the admin form: one "term: description"
+// per line, one do-not-mention entry per line and a percentage
+func parseGlossary(terms, doNotMention, percent string) (Glossary, error) {
+	glossary := Glossary{Probability: defaultGlossaryProbability}
+	if percent = strings.TrimSpace(percent); percent != "" {
+		// The range check is written to reject NaN as well
+		value, err := strconv.ParseFloat(percent, 64)
+		if err != nil || !(value >= 0 && value <= 100) {
+			return Glossary{}, fmt.Errorf("probability must be a percentage from 0 to 100")
+		}
+		glossary.Probability = value / 100
+	}
+
+	for _, line := range strings.Split(terms, "\n") {
+		term, description, _ := strings.Cut(line, ":")
+		if term = strings.TrimSpace(term); term != "" {
+			glossary.Terms = append(glossary.Terms, GlossaryTerm{Term: term, Description: strings.TrimSpace(description)})
+		}
+	}
+	for _, line := range strings.Split(doNotMention, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			glossary.DoNotMention = append(glossary.DoNotMention, line)
+		}
+	}
+	return glossary, nil
+}
+
+// TermLines returns the terms as the admin form shows them
+func (g Glossary) TermLines() string {
+	var b strings.Builder
+	for _, term := range g.Terms {
+		b.WriteString(term.Term)
+		if term.Description != "" {
+			b.WriteString(": " + term.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// DoNotMentionLines returns the do-not-mention list as the admin form shows it
+func (g Glossary) DoNotMentionLines() string {
+	if len(g.DoNotMention) == 0 {
+		return ""
+	}
+	return strings.Join(g.DoNotMention, "\n") + "\n"
+}
+
+// Percent returns the probability as the admin form shows it
+func (g Glossary) Percent() string {
+	return fmt.Sprintf("%g", g.Probability*100)
+}
+
+// sample picks a term for one request, or none, according to the probability
+func (g Glossary) sample() (GlossaryTerm, bool) {
+	if len(g.Terms) == 0 || rand.Float64() >= g.Probability {
+		return GlossaryTerm{}, false
+	}
+	return g.Terms[rand.Intn(len(g.Terms))], true
+}
+
+// currentGlossary returns the company glossary
+func (app *App) currentGlossary() Glossary {
+	app.glossaryMu.RLock()
+	defer app.glossaryMu.RUnlock()
+	return app.glossary
+}
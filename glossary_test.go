@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGlossary(t *testing.T) {
+	glossary, err := parseGlossary("Project Falcon: the billing rewrite\n\n  Blue Mug \n", "The Reorg\n", "25")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []GlossaryTerm{{Term: "Project Falcon", Description: "the billing rewrite"}, {Term: "Blue Mug"}}
+	if len(glossary.Terms) != len(want) || glossary.Terms[0] != want[0] || glossary.Terms[1] != want[1] {
+		t.Errorf("got terms %+v, want %+v", glossary.Terms, want)
+	}
+	if len(glossary.DoNotMention) != 1 || glossary.DoNotMention[0] != "The Reorg" {
+		t.Errorf("unexpected do-not-mention list %+v", glossary.DoNotMention)
+	}
+	if glossary.Probability != 0.25 {
+		t.Errorf("expected a probability of 0.25, got %v", glossary.Probability)
+	}
+
+	// The admin form shows the glossary as it was entered
+	if again, _ := parseGlossary(glossary.TermLines(), glossary.DoNotMentionLines(), glossary.Percent()); again.TermLines() != glossary.TermLines() || again.Probability != glossary.Probability {
+		t.Errorf("expected the form fields to round-trip, got %+v", again)
+	}
+
+	for _, percent := range []string{"150", "50abc", "NaN"} {
+		if _, err := parseGlossary("", "", percent); err == nil {
+			t.Errorf("expected a probability of %q to be rejected", percent)
+		}
+	}
+}
+
+func TestGlossarySample(t *testing.T) {
+	glossary := Glossary{Terms: []GlossaryTerm{{Term: "Blue Mug"}}}
+	for _, tt := range []struct {
+		probability float64
+		want        bool
+	}{{0, false}, {1, true}} {
+		glossary.Probability = tt.probability
+		for i := 0; i < 20; i++ {
+			if _, ok := glossary.sample(); ok != tt.want {
+				t.Fatalf("probability %v: expected sampled=%t", tt.probability, tt.want)
+			}
+		}
+	}
+}
+
+func TestModeratedGeneratorEnforcesDoNotMention(t *testing.T) {
+	inner := &scriptedIdeaGenerator{ideas: []string{"Reorg Rescue", "Mug Club"}}
+	generator := newModeratedGenerator(inner, NewBlocklist(nil), 1, nil)
+	ctx := context.Background()
+	opts := GenerationOptions{Glossary: Glossary{DoNotMention: []string{"reorg"}}}
+
+	if _, _, err := generator.GenerateBusinessIdea(ctx, opts); !errors.Is(err, ErrContentRejected) {
+		t.Errorf("expected an idea mentioning the reorg to be rejected, got %v", err)
+	}
+	if name, _, err := generator.GenerateBusinessIdea(ctx, opts); err != nil || name != "Mug Club" {
+		t.Errorf("expected the next idea to pass, got %q, %v", name, err)
+	}
+}
+
+func TestGlossaryHandlerDropsCachedMentions(t *testing.T) {
+	app := &App{
+		stateStore:   NewFileStateStore(filepath.Join(t.TempDir(), "state.json")),
+		contentCache: NewContentCache(5),
+	}
+	app.contentCache.Push(GameContent{BusinessName: "Reorg Rescue", Slogan: "Survive the shuffle."})
+	app.contentCache.Push(GameContent{BusinessName: "Mug Club", Slogan: "Never lose your mug."})
+
+	form := url.Values{"terms": {""}, "doNotMention": {"reorg\n"}, "probability": {"50"}}
+	req := httptest.NewRequest("POST", "/glossary", strings.NewReader(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	app.glossaryHandler(rr, req)
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusSeeOther)
+	}
+
+	if items := app.contentCache.Items(); len(items) != 1 || items[0].BusinessName != "Mug Club" {
+		t.Errorf("expected the deck mentioning the reorg to be dropped, got %+v", items)
+	}
+	app.contentCache.Push(GameContent{BusinessName: "Post-Reorg Pizza"})
+	if size := app.contentCache.Size(); size != 1 {
+		t.Errorf("expected later decks mentioning the reorg to be dropped too, got %d cached", size)
+	}
+}
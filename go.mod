@@ -6,6 +6,7 @@ toolchain go1.24.4
 
 require (
 	github.com/air-verse/air v1.62.0
+	golang.org/x/crypto v0.37.0
 	google.golang.org/genai v1.11.1
 )
 
@@ -41,7 +42,6 @@ require (
 	go.opentelemetry.io/otel v1.34.0 // indirect
 	go.opentelemetry.io/otel/metric v1.34.0 // indirect
 	go.opentelemetry.io/otel/trace v1.34.0 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
 	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,9 +25,13 @@ func (app *App) indexHandler(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		Participants []string
 		Next         string
+		Theme        Theme
+		CSRFToken    string
 	}{
 		Participants: app.participants,
 		Next:         nextParticipant,
+		Theme:        app.currentTheme(),
+		CSRFToken:    csrfToken(w, r),
 	}
 
 	app.templates.ExecuteTemplate(w, "index.html", data)
@@ -32,18 +41,55 @@ func (app *App) adminHandler(w http.ResponseWriter, r *http.Request) {
 	app.participantsMu.Lock()
 	defer app.participantsMu.Unlock()
 
+	type activeGame struct {
+		Session GameSession
+		Timer   GameTimerState
+	}
+	var games []activeGame
+	now := time.Now()
+	for _, session := range app.sessions.List() {
+		games = append(games, activeGame{Session: session, Timer: session.TimerState(now)})
+	}
+
+	maxCacheBytes := ""
+	if maxBytes := app.contentCache.MaxBytes(); maxBytes > 0 {
+		maxCacheBytes = formatBytes(maxBytes)
+	}
+
 	data := struct {
 		Participants   []string
 		CacheSize      int
 		CacheLoaded    bool
 		MaxCacheSize   int
+		CacheBytes     string
+		MaxCacheBytes  string
+		CacheStats     CacheStats
+		ReviewRequired bool
+		Theme          Theme
 		PreloadRunning bool
+		PreloadWorkers int
+		Scheduler      SchedulerStats
+		PreloadPlan    PreloadPlan
+		Games          []activeGame
+		SlideNumbers   []int
+		CSRFToken      string
 	}{
 		Participants:   app.participants,
 		CacheSize:      app.contentCache.Size(),
 		CacheLoaded:    app.contentCache.IsLoaded(),
 		MaxCacheSize:   app.contentCache.maxSize,
+		CacheBytes:     formatBytes(app.contentCache.SizeBytes()),
+		MaxCacheBytes:  maxCacheBytes,
+		CacheStats:     app.contentCache.Stats(),
+		ReviewRequired: app.contentCache.ReviewRequired(),
+		Theme:          app.currentTheme(),
 		PreloadRunning: app.isPreloadRunning(),
+		PreloadWorkers: app.preloadWorkers,
+		Scheduler:      app.scheduler.Stats(),
+		PreloadPlan:    app.currentPreloadPlan(),
+		Games:          games,
+		SlideNumbers:   []int{0, 1, 2, 3, 4},
+		CSRFToken:      csrfToken(w, r),
 	}
 	app.templates.ExecuteTemplate(w, "admin.html", data)
 }
@@ -60,6 +106,7 @@ func (app *App) participantsHandler(w http.ResponseWriter, r *http.Request) {
 	app.participantsMu.Lock()
 	defer app.participantsMu.Unlock()
 
+	removed := app.participants
 	app.participants = []string{}
 	for _, name := range newParticipants {
 		name = strings.TrimSpace(name)
@@ -67,10 +114,48 @@ func (app *App) participantsHandler(w http.ResponseWriter, r *http.Request) {
 			app.participants = append(app.participants, name)
 		}
 	}
+	app.saveStateLocked()
+	app.publishQueueLocked()
+	// Anyone taken out of the queue is done, as with removeParticipantHandler
+	for _, name := range removed {
+		if !slices.Contains(app.participants, name) {
+			app.sessions.Finish(name)
+		}
+	}
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+func (app *App) themeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	theme := parseTheme(r.FormValue("theme"), r.FormValue("keywords"))
+	if len(theme.String()) > maxThemeLength {
+		http.Error(w, fmt.Sprintf("Theme must be at most %d characters", maxThemeLength), http.StatusBadRequest)
+		return
+	}
+
+	app.participantsMu.Lock()
+	defer app.participantsMu.Unlock()
+
+	app.themeMu.Lock()
+	app.theme = theme
+	app.themeMu.Unlock()
+	app.saveStateLocked()
+
+	// Decks made for the old theme are no longer fit to show
+	if dropped := app.contentCache.SetTheme(theme.String()); dropped > 0 {
+		log.Printf("Dropped %d cached decks generated for the previous theme", dropped)
+	}
+	app.publish("theme", theme)
+	log.Printf("Event theme set to %q", theme.String())
+
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
 func (app *App) removeParticipantHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -93,6 +178,9 @@ func (app *App) removeParticipantHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 	app.participants = newParticipants
+	app.saveStateLocked()
+	app.publishQueueLocked()
+	app.sessions.Finish(nameToRemove)
 
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
@@ -107,7 +195,11 @@ func (app *App) nextParticipantHandler(w http.ResponseWriter, r *http.Request) {
 	defer app.participantsMu.Unlock()
 
 	if len(app.participants) > 0 {
+		// The current participant is done, so their next game gets a fresh deck
+		app.sessions.Finish(app.participants[0])
 		app.participants = app.participants[1:]
+		app.saveStateLocked()
+		app.publishQueueLocked()
 	}
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -118,45 +210,50 @@ func (app *App) gameHandler(w http.ResponseWriter, r *http.Request) {
 
 	data := struct {
 		ParticipantName string
+		CSRFToken       string
 	}{
 		ParticipantName: participantName,
+		CSRFToken:       csrfToken(w, r),
 	}
 
 	app.templates.ExecuteTemplate(w, "game.html", data)
 }
 
+// startGameSession returns the participant's active session, starting one
+// with a fresh deck if there is none. Reusing the session means a refresh
+// does not consume another deck.
+func (app *App) startGameSession(ctx context.Context, participantName string) (GameSession, error) {
+	return app.sessions.GetOrCreate(ctx, participantName, func(ctx context.Context) (*DeckBuild, error) {
+		return app.acquireGameContent(ctx, participantName)
+	})
+}
+
 func (app *App) gameDataHandler(w http.ResponseWriter, r *http.Request) {
 	participantName := strings.TrimPrefix(r.URL.Path, "/api/game-data/")
 
-	// Try to get content from cache first
-	content := app.contentCache.Pop()
-
-	if content == nil {
-		// Cache is empty - check if we should wait or generate on-demand
-		if !app.contentCache.IsLoaded() {
-			// Cache is still loading, wait a bit and try again
-			log.Printf("Cache not loaded yet, waiting for participant %s", participantName)
-			time.Sleep(2 * time.Second)
-			content = app.contentCache.Pop()
-		}
-
-		if content == nil {
-			// Still no content available, generate on-demand as fallback
-			log.Printf("Cache empty, generating content on-demand for participant %s", participantName)
+	session, err := app.startGameSession(r.Context(), participantName)
+	if err != nil {
+		log.Printf("Failed to start game session for participant %s: %v", participantName, err)
+		http.Error(w, "Failed to generate game content", http.StatusInternalServerError)
+		return
+	}
 
-			var err error
-			content, err = app.generateGameContent(r.Context())
-			if err != nil {
-				log.Printf("Failed to generate content on-demand: %v", err)
-				http.Error(w, "Failed to generate game content", http.StatusInternalServerError)
-				return
-			}
-		}
+	// This endpoint returns the whole deck at once, so wait for the images
+	deck, err := session.Deck().Wait(r.Context())
+	if err == nil {
+		err = deck.Err
+	}
+	if err != nil {
+		log.Printf("Failed to finish deck for game session %s: %v", session.ID, err)
+		http.Error(w, "Failed to generate game content", http.StatusInternalServerError)
+		return
 	}
 
-	log.Printf("Serving game content for participant %s (cache size: %d)", participantName, app.contentCache.Size())
+	log.Printf("Serving game session %s for participant %s (cache size: %d)", session.ID, participantName, app.contentCache.Size())
 
+	content := deck.Content
 	data := struct {
+		SessionID       string `json:"sessionId"`
 		ParticipantName string `json:"participantName"`
 		BusinessName    string `json:"businessName"`
 		Slogan          string `json:"slogan"`
@@ -164,11 +261,12 @@ func (app *App) gameDataHandler(w http.ResponseWriter, r *http.Request) {
 		Image2          string `json:"image2"`
 		ClappingGif     string `json:"clappingGif"`
 	}{
+		SessionID:       session.ID,
 		ParticipantName: participantName,
 		BusinessName:    content.BusinessName,
 		Slogan:          content.Slogan,
-		Image1:          content.Image1,
-		Image2:          content.Image2,
+		Image1:          imageURL(content.Image1, VariantDisplay),
+		Image2:          imageURL(content.Image2, VariantDisplay),
 		ClappingGif:     content.ClappingGif,
 	}
 
@@ -176,22 +274,434 @@ func (app *App) gameDataHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(data)
 }
 
-func (app *App) preloadCacheHandler(w http.ResponseWriter, r *http.Request) {
+// deckStreamEvent is one line of the game stream. Type says which fields are set.
+type deckStreamEvent struct {
+	Type            string `json:"type"` // session, pitch, image1, image2, clappingGif, done, error or failed
+	SessionID       string `json:"sessionId,omitempty"`
+	ParticipantName string `json:"participantName,omitempty"`
+	BusinessName    string `json:"businessName,omitempty"`
+	Slogan          string `json:"slogan,omitempty"`
+	URL             string `json:"url,omitempty"`
+	Message         string `json:"message,omitempty"`
+}
+
+// deckPartEvent describes one part of a deck for the game page
+func deckPartEvent(part string, content GameContent) deckStreamEvent {
+	switch part {
+	case SlidePitch:
+		return deckStreamEvent{Type: part, BusinessName: content.BusinessName, Slogan: content.Slogan}
+	case SlideImage1:
+		return deckStreamEvent{Type: part, URL: imageURL(content.Image1, VariantDisplay)}
+	case SlideImage2:
+		return deckStreamEvent{Type: part, URL: imageURL(content.Image2, VariantDisplay)}
+	}
+	return deckStreamEvent{Type: part, URL: content.ClappingGif}
+}
+
+// hasDeckPart reports whether a part of the deck has been generated
+func hasDeckPart(content GameContent, part string) bool {
+	switch part {
+	case SlidePitch:
+		return content.BusinessName != ""
+	case SlideImage1:
+		return content.Image1 != ""
+	case SlideImage2:
+		return content.Image2 != ""
+	}
+	return content.ClappingGif != ""
+}
+
+// gameStreamHandler streams the participant's deck as newline-delimited JSON,
+// one line per slide as its content becomes ready, so the talk can start on
+// the pitch while the images are still rendering
+func (app *App) gameStreamHandler(w http.ResponseWriter, r *http.Request) {
+	participantName := strings.TrimPrefix(r.URL.Path, "/api/game-stream/")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := app.startGameSession(r.Context(), participantName)
+	if err != nil {
+		log.Printf("Failed to start game session for participant %s: %v", participantName, err)
+		http.Error(w, "Failed to generate game content", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Streaming game session %s for participant %s (cache size: %d)", session.ID, participantName, app.contentCache.Size())
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	encoder := json.NewEncoder(w)
+	send := func(event deckStreamEvent) bool {
+		if err := encoder.Encode(event); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if !send(deckStreamEvent{Type: "session", SessionID: session.ID, ParticipantName: participantName}) {
+		return
+	}
+
+	deck := session.Deck()
+	var sent GameContent
+	for {
+		state, changed := deck.State()
+		content := state.Content
+
+		for _, part := range []string{SlidePitch, SlideImage1, SlideImage2, SlideClappingGif} {
+			if hasDeckPart(content, part) && !hasDeckPart(sent, part) {
+				if !send(deckPartEvent(part, content)) {
+					return
+				}
+			}
+		}
+		sent = content
+
+		if state.Done {
+			if errors.Is(state.Err, errSlidesMissing) {
+				send(deckStreamEvent{Type: "failed", Message: "Some slides could not be generated"})
+				return
+			}
+			if state.Err != nil {
+				log.Printf("Deck for game session %s failed: %v", session.ID, state.Err)
+				send(deckStreamEvent{Type: "error", Message: "Some slides are being regenerated"})
+				return
+			}
+			send(deckStreamEvent{Type: "done"})
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (app *App) gameStateHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := strings.TrimPrefix(r.URL.Path, "/api/game-state/")
+
+	session, ok := app.sessions.Get(sessionID)
+	if !ok {
+		http.Error(w, "Game session not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session.TimerState(time.Now()))
+}
+
+func (app *App) gameControlHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Generate one piece of content immediately
-	content, err := app.generateGameContent(r.Context())
+	sessionID := r.FormValue("session")
+	action := r.FormValue("action")
+
+	var err error
+	switch action {
+	case "pause":
+		_, err = app.sessions.Pause(sessionID)
+	case "resume":
+		_, err = app.sessions.Resume(sessionID)
+	case "restart":
+		_, err = app.sessions.Restart(sessionID)
+	case "skip":
+		slide, convErr := strconv.Atoi(r.FormValue("slide"))
+		if convErr != nil {
+			http.Error(w, "Invalid slide number", http.StatusBadRequest)
+			return
+		}
+		_, err = app.sessions.SkipToSlide(sessionID, slide)
+	default:
+		http.Error(w, "Unknown game action", http.StatusBadRequest)
+		return
+	}
+
+	if errors.Is(err, ErrSessionNotFound) {
+		http.Error(w, "Game session not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Applied game action %s to session %s", action, sessionID)
+
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+func (app *App) rerollSlideHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := r.FormValue("session")
+	part := r.FormValue("slide")
+	switch part {
+	case SlidePitch, SlideImage1, SlideImage2, SlideClappingGif:
+	default:
+		http.Error(w, "Unknown slide", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := app.sessions.Get(sessionID)
+	if !ok {
+		http.Error(w, "Game session not found", http.StatusNotFound)
+		return
+	}
+	if state, _ := session.Deck().State(); !state.Done {
+		http.Error(w, "The deck is still being generated", http.StatusConflict)
+		return
+	}
+
+	// Generating a slide takes a while, so the game page gets it over the
+	// event stream once it is ready. It counts against the generation limit
+	// like any deck, queued ahead of the preloader.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), rerollTimeout)
+		defer cancel()
+		err := app.scheduler.RunLive(ctx, func(ctx context.Context) error {
+			return app.rerollSlide(ctx, sessionID, part)
+		})
+		if err != nil {
+			log.Printf("Failed to reroll %s for session %s: %v", part, sessionID, err)
+			return
+		}
+		log.Printf("Rerolled %s for session %s", part, sessionID)
+	}()
+
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// acquireGameContent takes a deck from the cache, falling back to generating one
+// on-demand. Progress is reported to the presenter's page while they wait. An
+// on-demand deck is returned as soon as its pitch is ready.
+func (app *App) acquireGameContent(ctx context.Context, participantName string) (*DeckBuild, error) {
+	// Try to get content from cache first
+	content := app.contentCache.Pop()
+	if content != nil {
+		return completedDeck(*content), nil
+	}
+
+	// Fresh decks must be approved first, so wait for the host
+	if app.contentCache.ReviewRequired() {
+		log.Printf("No approved deck cached, waiting for the host to approve one for participant %s", participantName)
+		app.reportProgress(participantName, "waiting", "Waiting for the host to approve a presentation...")
+		content, err := app.contentCache.PopWait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return completedDeck(*content), nil
+	}
+
+	// The initial fill is underway, so a deck is likely moments away
+	if !app.contentCache.IsLoaded() && app.cacheWaitTimeout > 0 {
+		log.Printf("Cache not loaded yet, waiting up to %v for participant %s", app.cacheWaitTimeout, participantName)
+		app.reportProgress(participantName, "waiting", "Waiting for the first presentations to finish preloading...")
+
+		waitCtx, cancel := context.WithTimeout(ctx, app.cacheWaitTimeout)
+		content, err := app.contentCache.PopWait(waitCtx)
+		cancel()
+		if content != nil {
+			return completedDeck(*content), nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("No deck preloaded within %v for participant %s: %v", app.cacheWaitTimeout, participantName, err)
+	}
+
+	// Still no content available; wait for the first deck any generation
+	// finishes, with a live generation queued ahead of the preloader
+	log.Printf("Cache empty, waiting for an on-demand deck for participant %s", participantName)
+	app.reportProgress(participantName, "generating", "Generating a fresh presentation just for you...")
+	deck, err := app.scheduler.Await(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content on-demand: %w", err)
+	}
+	return deck, nil
+}
+
+func (app *App) preloadCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Generate one piece of content immediately; a waiting presenter gets it first
+	if err := app.scheduler.Produce(r.Context(), PriorityPreload); err != nil {
 		log.Printf("Failed to generate content manually: %v", err)
 		http.Error(w, "Failed to generate content", http.StatusInternalServerError)
 		return
 	}
 
-	app.contentCache.Push(*content)
 	log.Printf("Manually generated content. Cache size: %d", app.contentCache.Size())
 
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
+
+func (app *App) decksHandler(w http.ResponseWriter, r *http.Request) {
+	type cachedDeck struct {
+		GameContent
+		Thumb1, Thumb2 string
+		Age            string
+	}
+	var decks []cachedDeck
+	now := time.Now()
+	for _, content := range app.contentCache.Items() {
+		deck := cachedDeck{
+			GameContent: content,
+			Thumb1:      imageURL(content.Image1, VariantThumb),
+			Thumb2:      imageURL(content.Image2, VariantThumb),
+		}
+		if !content.CreatedAt.IsZero() {
+			deck.Age = now.Sub(content.CreatedAt).Round(time.Minute).String()
+		}
+		decks = append(decks, deck)
+	}
+
+	data := struct {
+		Decks          []cachedDeck
+		ReviewRequired bool
+		CSRFToken      string
+	}{
+		Decks:          decks,
+		ReviewRequired: app.contentCache.ReviewRequired(),
+		CSRFToken:      csrfToken(w, r),
+	}
+	app.templates.ExecuteTemplate(w, "decks.html", data)
+}
+
+func (app *App) deckControlHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.FormValue("id")
+	var found bool
+	switch action := r.FormValue("action"); action {
+	case "approve":
+		found = app.contentCache.Approve(id)
+	case "delete":
+		found = app.contentCache.Remove(id)
+	case "regenerate":
+		found = app.contentCache.Remove(id)
+		if found {
+			// Replace the deck without making the host wait for it
+			go func() {
+				if err := app.scheduler.Produce(context.Background(), PriorityPreload); err != nil {
+					log.Printf("Failed to regenerate deck: %v", err)
+				}
+			}()
+		}
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	if !found {
+		http.Error(w, "Deck not found", http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, "/decks", http.StatusSeeOther)
+}
+
+func (app *App) reviewModeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	app.participantsMu.Lock()
+	defer app.participantsMu.Unlock()
+
+	required := r.FormValue("required") == "true"
+	app.contentCache.SetReviewRequired(required)
+	app.saveStateLocked()
+	if required {
+		// Presenters already waiting must not get an unreviewed deck either
+		app.scheduler.AwaitReview()
+	}
+
+	http.Redirect(w, r, "/decks", http.StatusSeeOther)
+}
+
+func (app *App) promptsHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Pack      string
+		Path      string
+		Error     string
+		CSRFToken string
+	}{
+		Pack:      app.prompts.JSON(),
+		Path:      app.prompts.Path(),
+		CSRFToken: csrfToken(w, r),
+	}
+
+	if r.Method == http.MethodPost {
+		pack := r.FormValue("pack")
+		if err := app.prompts.Save([]byte(pack)); err != nil {
+			log.Printf("Rejected prompt pack edit: %v", err)
+			data.Pack = pack
+			data.Error = err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			app.templates.ExecuteTemplate(w, "prompts.html", data)
+			return
+		}
+		log.Printf("Saved prompt pack to %s", app.prompts.Path())
+		http.Redirect(w, r, "/prompts", http.StatusSeeOther)
+		return
+	}
+
+	app.templates.ExecuteTemplate(w, "prompts.html", data)
+}
+
+func (app *App) glossaryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		glossary, err := parseGlossary(r.FormValue("terms"), r.FormValue("doNotMention"), r.FormValue("probability"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		app.participantsMu.Lock()
+		app.glossaryMu.Lock()
+		app.glossary = glossary
+		app.glossaryMu.Unlock()
+		app.saveStateLocked()
+		app.participantsMu.Unlock()
+
+		// Decks generated before an entry was added must not reach the projector
+		if dropped := app.contentCache.SetDoNotMention(glossary.DoNotMention); dropped > 0 {
+			log.Printf("Dropped %d cached decks mentioning the do-not-mention list", dropped)
+		}
+
+		log.Printf("Glossary updated: %d terms, %d do-not-mention entries, %s%% of requests", len(glossary.Terms), len(glossary.DoNotMention), glossary.Percent())
+		http.Redirect(w, r, "/glossary", http.StatusSeeOther)
+		return
+	}
+
+	glossary := app.currentGlossary()
+	if glossary.IsZero() && glossary.Probability == 0 {
+		glossary.Probability = defaultGlossaryProbability
+	}
+	data := struct {
+		Glossary  Glossary
+		CSRFToken string
+	}{
+		Glossary:  glossary,
+		CSRFToken: csrfToken(w, r),
+	}
+	app.templates.ExecuteTemplate(w, "glossary.html", data)
+}
@@ -2,32 +2,36 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // MockGenerator is a mock implementation of the Generator interface for testing.
 type MockGenerator struct{}
 
-func (m *MockGenerator) GenerateBusinessIdea(ctx context.Context) (string, string, error) {
+func (m *MockGenerator) GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error) {
 	return "Test Business", "Test Slogan", nil
 }
 
-func (m *MockGenerator) GenerateImagePrompt(ctx context.Context) (string, error) {
+func (m *MockGenerator) GenerateImagePrompt(ctx context.Context, brief *DeckBrief, opts GenerationOptions) (string, error) {
 	return "a test image prompt", nil
 }
 
-func (m *MockGenerator) GenerateImage(ctx context.Context, prompt string) (string, error) {
-	return "data:image/png;base64,test", nil
+func (m *MockGenerator) GenerateImage(ctx context.Context, prompt string) (*GeneratedImage, error) {
+	return &GeneratedImage{Data: []byte("test"), MIMEType: "image/png"}, nil
 }
 
 func TestParticipantsHandler(t *testing.T) {
 	app := &App{
 		templates: template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		sessions:  NewSessionManager(),
 	}
 
 	form := url.Values{}
@@ -58,12 +62,34 @@ func TestParticipantsHandler(t *testing.T) {
 			t.Errorf("expected participant %s, got %s", expectedParticipants[i], p)
 		}
 	}
+
+	// Replacing the queue finishes the games of anyone taken out of it
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := app.sessions.GetOrCreate(context.Background(), name, func(ctx context.Context) (*DeckBuild, error) {
+			return completedDeck(GameContent{BusinessName: "Test Business"}), nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	form.Set("names", "Bob\nDana")
+	req = httptest.NewRequest("POST", "/participants", strings.NewReader(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	sessions := app.sessions.List()
+	if len(sessions) != 1 || sessions[0].ParticipantName != "Bob" {
+		t.Errorf("expected only Bob's game to keep running, got %+v", sessions)
+	}
 }
 
 func TestGameDataHandler(t *testing.T) {
 	app := &App{
-		generator: &MockGenerator{},
+		generator:    &MockGenerator{},
+		contentCache: NewContentCache(1),
+		images:       NewMemoryImageStore(),
+		sessions:     NewSessionManager(),
 	}
+	app.scheduler = NewGenerationScheduler(1, app.contentCache, app.generateGameContent)
 
 	req, err := http.NewRequest("GET", "/api/game-data/test-participant", nil)
 	if err != nil {
@@ -81,3 +107,492 @@ func TestGameDataHandler(t *testing.T) {
 
 	// Add more assertions here to check the response body
 }
+
+func TestAcquireGameContentWaitsForInitialFill(t *testing.T) {
+	app := &App{
+		contentCache:     NewContentCache(5),
+		cacheWaitTimeout: time.Second,
+		events:           NewEventBroker(),
+	}
+	events, unsubscribe := app.events.Subscribe()
+	defer unsubscribe()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		app.contentCache.Push(GameContent{BusinessName: "Preloaded"})
+	}()
+
+	// No scheduler: falling through to on-demand generation would panic
+	deck, err := app.acquireGameContent(context.Background(), "Alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state, _ := deck.State(); state.Content.BusinessName != "Preloaded" || !state.Done {
+		t.Errorf("expected the preloaded deck, got %+v", state)
+	}
+
+	event := <-events
+	progress, ok := event.Data.(progressEvent)
+	if event.Type != "progress" || !ok || progress.ParticipantName != "Alice" || progress.Stage != "waiting" {
+		t.Errorf("expected a waiting progress event for Alice, got %+v", event)
+	}
+}
+
+func TestParticipantsPersistAcrossRestart(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	app := &App{
+		templates:  template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		stateStore: NewFileStateStore(stateFile),
+		sessions:   NewSessionManager(),
+	}
+
+	form := url.Values{}
+	form.Add("names", "Alice\nBob\nCharlie")
+	req := httptest.NewRequest("POST", "/participants", strings.NewReader(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	app.participantsHandler(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("POST", "/next-participant", nil)
+	app.nextParticipantHandler(httptest.NewRecorder(), req)
+
+	restarted := &App{stateStore: NewFileStateStore(stateFile)}
+	if err := restarted.loadState(); err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	expectedParticipants := []string{"Bob", "Charlie"}
+	if len(restarted.participants) != len(expectedParticipants) {
+		t.Fatalf("expected %d participants after restart, got %v", len(expectedParticipants), restarted.participants)
+	}
+	for i, p := range restarted.participants {
+		if p != expectedParticipants[i] {
+			t.Errorf("expected participant %s, got %s", expectedParticipants[i], p)
+		}
+	}
+}
+
+func TestThemeHandler(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	app := &App{
+		stateStore:   NewFileStateStore(stateFile),
+		contentCache: NewContentCache(5),
+	}
+	app.contentCache.Push(GameContent{BusinessName: "Unthemed"})
+
+	form := url.Values{"theme": {"Space week"}, "keywords": {" retro-futurist, , neon "}}
+	req := httptest.NewRequest("POST", "/theme", strings.NewReader(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	app.themeHandler(rr, req)
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusSeeOther)
+	}
+
+	theme := app.currentTheme()
+	if theme.Text != "Space week" || len(theme.Keywords) != 2 || theme.Keywords[1] != "neon" {
+		t.Errorf("unexpected theme %+v", theme)
+	}
+	if app.contentCache.Size() != 0 {
+		t.Error("expected decks from before the theme to be dropped")
+	}
+	app.contentCache.Push(GameContent{BusinessName: "Stale", Theme: ""})
+	app.contentCache.Push(GameContent{BusinessName: "Themed", Theme: theme.String()})
+	if items := app.contentCache.Items(); len(items) != 1 || items[0].BusinessName != "Themed" {
+		t.Errorf("expected only the themed deck to be cached, got %+v", items)
+	}
+	if stats := app.contentCache.Stats(); stats != (CacheStats{Rethemed: 2}) {
+		t.Errorf("expected the off-theme decks to be counted apart from stale ones, got %+v", stats)
+	}
+
+	restarted := &App{stateStore: NewFileStateStore(stateFile), contentCache: NewContentCache(5)}
+	if err := restarted.loadState(); err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if got := restarted.currentTheme().String(); got != theme.String() {
+		t.Errorf("expected the theme to survive a restart, got %q", got)
+	}
+}
+
+func TestGameDataHandlerReusesSession(t *testing.T) {
+	app := &App{
+		generator:    &MockGenerator{},
+		contentCache: NewContentCache(5),
+		sessions:     NewSessionManager(),
+	}
+	app.contentCache.Push(GameContent{BusinessName: "First Deck"})
+	app.contentCache.Push(GameContent{BusinessName: "Second Deck"})
+
+	fetch := func() map[string]string {
+		rr := httptest.NewRecorder()
+		app.gameDataHandler(rr, httptest.NewRequest("GET", "/api/game-data/Alice", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var data map[string]string
+		if err := json.NewDecoder(rr.Body).Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	first := fetch()
+	second := fetch()
+	if first["sessionId"] == "" || first["sessionId"] != second["sessionId"] {
+		t.Errorf("expected the same session on refresh, got %q and %q", first["sessionId"], second["sessionId"])
+	}
+	if second["businessName"] != "First Deck" {
+		t.Errorf("expected refresh to keep the first deck, got %q", second["businessName"])
+	}
+	if size := app.contentCache.Size(); size != 1 {
+		t.Errorf("expected only one deck to be consumed, cache size is %d", size)
+	}
+
+	app.sessions.Finish("Alice")
+	if third := fetch(); third["sessionId"] == first["sessionId"] || third["businessName"] != "Second Deck" {
+		t.Errorf("expected a new session with the next deck after finishing, got %v", third)
+	}
+}
+
+func TestGameStreamHandler(t *testing.T) {
+	app := &App{
+		contentCache: NewContentCache(1),
+		sessions:     NewSessionManager(),
+	}
+	app.contentCache.Push(GameContent{BusinessName: "Test Business", Slogan: "Test Slogan", Image1: "image1", Image2: "image2", ClappingGif: "gif"})
+
+	rr := httptest.NewRecorder()
+	app.gameStreamHandler(rr, httptest.NewRequest("GET", "/api/game-stream/Alice", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var types []string
+	decoder := json.NewDecoder(rr.Body)
+	for decoder.More() {
+		var event deckStreamEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("invalid stream line: %v", err)
+		}
+		if event.Type == "pitch" && event.BusinessName != "Test Business" {
+			t.Errorf("unexpected pitch %+v", event)
+		}
+		types = append(types, event.Type)
+	}
+	if got := strings.Join(types, ","); got != "session,pitch,image1,image2,clappingGif,done" {
+		t.Errorf("unexpected stream %s", got)
+	}
+}
+
+func TestGameControlHandler(t *testing.T) {
+	app := &App{
+		contentCache: NewContentCache(1),
+		sessions:     NewSessionManager(),
+	}
+	app.contentCache.Push(GameContent{BusinessName: "Test Business"})
+	session, err := app.sessions.GetOrCreate(context.Background(), "Alice", func(ctx context.Context) (*DeckBuild, error) {
+		return completedDeck(*app.contentCache.Pop()), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	control := func(form url.Values) int {
+		form.Set("session", session.ID)
+		req := httptest.NewRequest("POST", "/game-control", strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		app.gameControlHandler(rr, req)
+		return rr.Code
+	}
+
+	if status := control(url.Values{"action": {"skip"}, "slide": {"3"}}); status != http.StatusSeeOther {
+		t.Fatalf("skip returned wrong status code: got %v want %v", status, http.StatusSeeOther)
+	}
+	if status := control(url.Values{"action": {"pause"}}); status != http.StatusSeeOther {
+		t.Fatalf("pause returned wrong status code: got %v want %v", status, http.StatusSeeOther)
+	}
+	if status := control(url.Values{"action": {"skip"}, "slide": {"9"}}); status != http.StatusBadRequest {
+		t.Errorf("out of range skip returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	rr := httptest.NewRecorder()
+	app.gameStateHandler(rr, httptest.NewRequest("GET", "/api/game-state/"+session.ID, nil))
+	var state GameTimerState
+	if err := json.NewDecoder(rr.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if !state.Paused || state.CurrentSlide != 3 {
+		t.Errorf("expected paused on slide 3, got %+v", state)
+	}
+	if state.RemainingMs > (gameDuration - 3*slideInterval).Milliseconds() {
+		t.Errorf("expected at most %v remaining, got %dms", gameDuration-3*slideInterval, state.RemainingMs)
+	}
+
+	rr = httptest.NewRecorder()
+	app.gameStateHandler(rr, httptest.NewRequest("GET", "/api/game-state/unknown", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown session, got %v", rr.Code)
+	}
+}
+
+func TestDeckControlHandler(t *testing.T) {
+	app := &App{
+		templates:    template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		contentCache: NewContentCache(5),
+	}
+	app.contentCache.Push(GameContent{BusinessName: "Keep Me"})
+	app.contentCache.Push(GameContent{BusinessName: "Bin Me"})
+	items := app.contentCache.Items()
+
+	rr := httptest.NewRecorder()
+	app.decksHandler(rr, httptest.NewRequest("GET", "/decks", nil))
+	if !strings.Contains(rr.Body.String(), "Keep Me") || !strings.Contains(rr.Body.String(), "Bin Me") {
+		t.Fatal("expected the decks page to list every cached deck")
+	}
+
+	control := func(form url.Values) int {
+		req := httptest.NewRequest("POST", "/deck-control", strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		app.deckControlHandler(rr, req)
+		return rr.Code
+	}
+
+	if status := control(url.Values{"action": {"approve"}, "id": {items[0].ID}}); status != http.StatusSeeOther {
+		t.Fatalf("approve returned wrong status code: got %v want %v", status, http.StatusSeeOther)
+	}
+	if status := control(url.Values{"action": {"delete"}, "id": {items[1].ID}}); status != http.StatusSeeOther {
+		t.Fatalf("delete returned wrong status code: got %v want %v", status, http.StatusSeeOther)
+	}
+	if status := control(url.Values{"action": {"delete"}, "id": {items[1].ID}}); status != http.StatusNotFound {
+		t.Errorf("deleting twice returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+
+	remaining := app.contentCache.Items()
+	if len(remaining) != 1 || remaining[0].BusinessName != "Keep Me" || !remaining[0].Approved {
+		t.Errorf("expected only the approved deck to remain, got %+v", remaining)
+	}
+}
+
+func TestRerollSlideHandler(t *testing.T) {
+	app := &App{
+		generator: &MockGenerator{},
+		sessions:  NewSessionManager(),
+		events:    NewEventBroker(),
+	}
+	app.scheduler = NewGenerationScheduler(1, nil, nil)
+	pending := newDeckBuild()
+	session, err := app.sessions.GetOrCreate(context.Background(), "Alice", func(ctx context.Context) (*DeckBuild, error) {
+		return pending, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := app.sessions.GetOrCreate(context.Background(), "Bob", func(ctx context.Context) (*DeckBuild, error) {
+		return completedDeck(GameContent{BusinessName: "Old Business"}), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another generation holds the only slot
+	if err := app.scheduler.acquire(context.Background(), PriorityPreload); err != nil {
+		t.Fatal(err)
+	}
+
+	reroll := func(form url.Values) int {
+		req := httptest.NewRequest("POST", "/reroll-slide", strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		app.rerollSlideHandler(rr, req)
+		return rr.Code
+	}
+
+	tests := []struct {
+		name string
+		form url.Values
+		want int
+	}{
+		{"unknown slide", url.Values{"session": {session.ID}, "slide": {"welcome"}}, http.StatusBadRequest},
+		{"unknown session", url.Values{"session": {"missing"}, "slide": {SlideImage1}}, http.StatusNotFound},
+		{"deck still generating", url.Values{"session": {session.ID}, "slide": {SlideImage1}}, http.StatusConflict},
+		{"every slot busy", url.Values{"session": {done.ID}, "slide": {SlidePitch}}, http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		if status := reroll(tt.form); status != tt.want {
+			t.Errorf("%s: got status %v want %v", tt.name, status, tt.want)
+		}
+	}
+
+	// The reroll queues for the slot rather than failing or bypassing the limit
+	waitFor(t, "reroll to queue", func() bool { return app.scheduler.Stats().QueuedLive == 1 })
+	if current, _ := app.sessions.Get(done.ID); current.Content.BusinessName != "Old Business" {
+		t.Fatalf("expected the reroll to wait for a slot, got %+v", current.Content)
+	}
+	app.scheduler.release()
+	waitFor(t, "reroll to finish", func() bool {
+		current, _ := app.sessions.Get(done.ID)
+		return current.Content.BusinessName == "Test Business"
+	})
+}
+
+func TestParticipantsHandlerPublishesQueue(t *testing.T) {
+	app := &App{
+		templates: template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		events:    NewEventBroker(),
+	}
+	events, unsubscribe := app.events.Subscribe()
+	defer unsubscribe()
+
+	form := url.Values{}
+	form.Add("names", "Alice\nBob")
+	req := httptest.NewRequest("POST", "/participants", strings.NewReader(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	app.participantsHandler(httptest.NewRecorder(), req)
+
+	select {
+	case event := <-events:
+		queue, ok := event.Data.(queueEvent)
+		if event.Type != "queue" || !ok {
+			t.Fatalf("expected a queue event, got %+v", event)
+		}
+		if queue.Next != "Alice" || len(queue.Participants) != 2 {
+			t.Errorf("unexpected queue event payload: %+v", queue)
+		}
+	default:
+		t.Fatal("expected a queue event to be published")
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	app := &App{
+		templates: template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		auth:      NewPasswordAuthenticator("secret"),
+	}
+	handler := app.requireAdmin(app.participantsHandler)
+
+	postNames := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		form := url.Values{}
+		form.Add("names", "Mallory")
+		req := httptest.NewRequest("POST", "/participants", strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := postNames()
+	if rr.Code != http.StatusSeeOther || !strings.HasPrefix(rr.Header().Get("Location"), "/login") {
+		t.Fatalf("expected redirect to login, got %v %q", rr.Code, rr.Header().Get("Location"))
+	}
+	if len(app.participants) != 0 {
+		t.Fatalf("unauthenticated request changed the queue: %v", app.participants)
+	}
+
+	login := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}, "next": {"/admin"}}
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		app.loginHandler(rr, req)
+		return rr
+	}
+
+	if rr := login("wrong"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected wrong password to be rejected, got %v", rr.Code)
+	}
+
+	rr = login("secret")
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin" {
+		t.Fatalf("expected redirect to /admin after login, got %v %q", rr.Code, rr.Header().Get("Location"))
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != adminCookieName {
+		t.Fatalf("expected an admin session cookie, got %v", cookies)
+	}
+
+	if rr := postNames(cookies[0]); rr.Code != http.StatusSeeOther || len(app.participants) != 1 {
+		t.Errorf("expected authenticated request to update the queue, got %v %v", rr.Code, app.participants)
+	}
+}
+
+func TestCSRFProtect(t *testing.T) {
+	app := &App{
+		templates:    template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		contentCache: NewContentCache(1),
+		sessions:     NewSessionManager(),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin", app.adminHandler)
+	mux.HandleFunc("/participants", app.participantsHandler)
+	handler := csrfProtect(mux)
+
+	// Rendering the admin page issues a token and embeds it in its forms
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/admin", nil))
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookieName {
+		t.Fatalf("expected a CSRF cookie, got %v", cookies)
+	}
+	token := cookies[0].Value
+	if !strings.Contains(rr.Body.String(), `name="csrf_token" value="`+token+`"`) {
+		t.Error("expected admin forms to embed the CSRF token")
+	}
+
+	post := func(formToken string, cookie *http.Cookie) int {
+		form := url.Values{"names": {"Alice"}}
+		if formToken != "" {
+			form.Set(csrfFieldName, formToken)
+		}
+		req := httptest.NewRequest("POST", "/participants", strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if status := post("", nil); status != http.StatusForbidden {
+		t.Errorf("expected POST without a token to be rejected, got %v", status)
+	}
+	if status := post("", cookies[0]); status != http.StatusForbidden {
+		t.Errorf("expected POST with only the cookie to be rejected, got %v", status)
+	}
+	if status := post("forged", cookies[0]); status != http.StatusForbidden {
+		t.Errorf("expected POST with a mismatched token to be rejected, got %v", status)
+	}
+	if len(app.participants) != 0 {
+		t.Fatalf("rejected requests changed the queue: %v", app.participants)
+	}
+	if status := post(token, cookies[0]); status != http.StatusSeeOther {
+		t.Errorf("expected POST with a valid token to succeed, got %v", status)
+	}
+	if len(app.participants) != 1 {
+		t.Errorf("expected valid request to update the queue, got %v", app.participants)
+	}
+}
+
+func TestTemplatesEmbedCSRFToken(t *testing.T) {
+	app := &App{
+		templates: template.Must(template.ParseFS(templateFS, "templates/*.html")),
+		auth:      NewPasswordAuthenticator("secret"),
+	}
+	pages := map[string]http.HandlerFunc{
+		"/":         app.indexHandler,
+		"/game/Bob": app.gameHandler,
+		"/login":    app.loginHandler,
+	}
+	for path, handler := range pages {
+		req := httptest.NewRequest("GET", path, nil)
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "0123456789abcdef0123456789abcdef"})
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		if !strings.Contains(rr.Body.String(), `<meta name="csrf-token" content="0123456789abcdef0123456789abcdef">`) {
+			t.Errorf("%s: expected page to embed the CSRF token", path)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	imagePruneInterval = 10 * time.Minute
+	// Images younger than this are never pruned, so decks that are still
+	// generating keep theirs before anything refers to them
+	imagePruneGrace = time.Hour
+)
+
+// ErrImageNotFound is returned when no image is stored under a name
+var ErrImageNotFound = errors.New("image not found")
+
+// GeneratedImage is the raw output of an image model
+type GeneratedImage struct {
+	Data     []byte
+	MIMEType string
+}
+
+// ImageStore keeps images under names derived from their content, so the
+// same image is only stored once and a name always means the same bytes
+type ImageStore interface {
+	// Put stores an image and returns its name
+	Put(data []byte, mimeType string) (string, error)
+	// PutVariant stores a JPEG rendition of the named image and returns its name
+	PutVariant(name, variant string, data []byte) (string, error)
+	Get(name string) ([]byte, error)
+	Has(name string) bool
+	// Size returns the stored size of an image, or zero if it is missing
+	Size(name string) int64
+	// Prune removes images not in keep, along with their variants, that were
+	// stored before cutoff
+	Prune(keep map[string]bool, cutoff time.Time) error
+}
+
+// imageName returns the content-addressed name for an image: the SHA-256 of
+// its bytes with an extension from its MIME type
+func imageName(data []byte, mimeType string) string {
+	ext := strings.TrimPrefix(mimeType, "image/")
+	if ext == "" || strings.ContainsAny(ext, "/.;") {
+		ext = "bin"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) + "." + ext
+}
+
+// variantName returns the name a variant of the named image is stored under.
+// Variants derive from the original's bytes, so they share its hash.
+func variantName(name, variant string) string {
+	return imageHash(name) + "-" + variant + ".jpeg"
+}
+
+// imageHash returns the content hash part of an image or variant name
+func imageHash(name string) string {
+	end := strings.IndexAny(name, "-.")
+	if end < 0 {
+		return name
+	}
+	return name[:end]
+}
+
+// validImageName reports whether name looks like one imageName or
+// variantName produced
+func validImageName(name string) bool {
+	base, ext, ok := strings.Cut(name, ".")
+	if !ok || ext == "" || strings.ContainsAny(ext, "/\\.") {
+		return false
+	}
+	hash, variant, hasVariant := strings.Cut(base, "-")
+	if hasVariant {
+		if _, ok := imageVariants[variant]; !ok {
+			return false
+		}
+	}
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// imageURL returns where the browser fetches a variant of a stored image
+func imageURL(name, variant string) string {
+	if name == "" {
+		return ""
+	}
+	if variant == "" || variant == VariantOriginal {
+		return "/images/" + name
+	}
+	return "/images/" + name + "?variant=" + variant
+}
+
+// FileImageStore keeps images as files in a directory
+type FileImageStore struct {
+	dir string
+}
+
+// NewFileImageStore creates a store that keeps images in dir
+func NewFileImageStore(dir string) *FileImageStore {
+	return &FileImageStore{dir: dir}
+}
+
+func (s *FileImageStore) Put(data []byte, mimeType string) (string, error) {
+	name := imageName(data, mimeType)
+	path := filepath.Join(s.dir, name)
+
+	// Already stored; refresh its age so it is not pruned from under a new deck
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err == nil {
+		return name, nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create image directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *FileImageStore) PutVariant(name, variant string, data []byte) (string, error) {
+	variantName := variantName(name, variant)
+	if err := writeFileAtomic(filepath.Join(s.dir, variantName), data); err != nil {
+		return "", err
+	}
+	return variantName, nil
+}
+
+func (s *FileImageStore) Get(name string) ([]byte, error) {
+	if !validImageName(name) {
+		return nil, ErrImageNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	return data, nil
+}
+
+func (s *FileImageStore) Has(name string) bool {
+	if !validImageName(name) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(s.dir, name))
+	return err == nil
+}
+
+func (s *FileImageStore) Size(name string) int64 {
+	if !validImageName(name) {
+		return 0
+	}
+	info, err := os.Stat(filepath.Join(s.dir, name))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func (s *FileImageStore) Prune(keep map[string]bool, cutoff time.Time) error {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list image directory: %w", err)
+	}
+
+	keepHashes := imageHashes(keep)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || keepHashes[imageHash(name)] || strings.HasPrefix(name, ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+			log.Printf("Failed to prune image %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// MemoryImageStore keeps images in memory, for tests
+type MemoryImageStore struct {
+	mu     sync.Mutex
+	images map[string][]byte
+	stored map[string]time.Time
+}
+
+// NewMemoryImageStore creates an empty in-memory image store
+func NewMemoryImageStore() *MemoryImageStore {
+	return &MemoryImageStore{
+		images: make(map[string][]byte),
+		stored: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryImageStore) Put(data []byte, mimeType string) (string, error) {
+	name := imageName(data, mimeType)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images[name] = append([]byte(nil), data...)
+	s.stored[name] = time.Now()
+	return name, nil
+}
+
+func (s *MemoryImageStore) PutVariant(name, variant string, data []byte) (string, error) {
+	variantName := variantName(name, variant)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images[variantName] = append([]byte(nil), data...)
+	s.stored[variantName] = time.Now()
+	return variantName, nil
+}
+
+func (s *MemoryImageStore) Get(name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.images[name]
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+	return data, nil
+}
+
+func (s *MemoryImageStore) Has(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.images[name]
+	return ok
+}
+
+func (s *MemoryImageStore) Size(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.images[name]))
+}
+
+func (s *MemoryImageStore) Prune(keep map[string]bool, cutoff time.Time) error {
+	keepHashes := imageHashes(keep)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, stored := range s.stored {
+		if !keepHashes[imageHash(name)] && !stored.After(cutoff) {
+			delete(s.images, name)
+			delete(s.stored, name)
+		}
+	}
+	return nil
+}
+
+// imageHashes returns the content hashes of the named images
+func imageHashes(names map[string]bool) map[string]bool {
+	hashes := make(map[string]bool, len(names))
+	for name := range names {
+		hashes[imageHash(name)] = true
+	}
+	return hashes
+}
+
+// storeImage saves a generated image along with its variants and returns its
+// name. A variant that cannot be rendered now is rendered when first requested.
+func (app *App) storeImage(image *GeneratedImage) (string, error) {
+	name, err := app.images.Put(image.Data, image.MIMEType)
+	if err != nil {
+		return "", fmt.Errorf("failed to store image: %w", err)
+	}
+	for variant := range imageVariants {
+		if _, err := app.storeVariant(name, image.Data, variant); err != nil {
+			log.Printf("Failed to prepare %s variant of image %s: %v", variant, name, err)
+		}
+	}
+	return name, nil
+}
+
+// storeVariant renders and stores one variant of an image
+func (app *App) storeVariant(name string, data []byte, variant string) ([]byte, error) {
+	rendered, err := renderVariant(data, imageVariants[variant])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := app.images.PutVariant(name, variant, rendered); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// loadImage returns the bytes and stored name of a variant of an image,
+// rendering the variant if it is missing
+func (app *App) loadImage(name, variant string) ([]byte, string, error) {
+	if variant == VariantOriginal {
+		data, err := app.images.Get(name)
+		return data, name, err
+	}
+
+	variantName := variantName(name, variant)
+	data, err := app.images.Get(variantName)
+	if !errors.Is(err, ErrImageNotFound) {
+		return data, variantName, err
+	}
+
+	// Stored before variants existed, or rendering failed at the time
+	original, err := app.images.Get(name)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err = app.storeVariant(name, original, variant)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to render %s variant: %w", variant, err)
+	}
+	return data, variantName, nil
+}
+
+// deckSize returns the bytes a cached deck keeps alive: its text plus its
+// images and their variants
+func (app *App) deckSize(content GameContent) int64 {
+	size := gameContentTextSize(content)
+	for _, name := range []string{content.Image1, content.Image2} {
+		if name == "" {
+			continue
+		}
+		size += app.images.Size(name)
+		for variant := range imageVariants {
+			size += app.images.Size(variantName(name, variant))
+		}
+	}
+	return size
+}
+
+// referencedImages returns the names of every image a cached deck or an
+// active game still shows
+func (app *App) referencedImages() map[string]bool {
+	referenced := make(map[string]bool)
+	add := func(content GameContent) {
+		referenced[content.Image1] = true
+		referenced[content.Image2] = true
+	}
+	for _, content := range app.contentCache.Items() {
+		add(content)
+	}
+	for _, session := range app.sessions.List() {
+		add(session.Content)
+	}
+	return referenced
+}
+
+// pruneImages removes stored images that nothing refers to any more
+func (app *App) pruneImages(now time.Time) {
+	if err := app.images.Prune(app.referencedImages(), now.Add(-imagePruneGrace)); err != nil {
+		log.Printf("Failed to prune images: %v", err)
+	}
+}
+
+// runImagePruner prunes unreferenced images periodically
+func (app *App) runImagePruner() {
+	ticker := time.NewTicker(imagePruneInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		app.pruneImages(now)
+	}
+}
+
+// imageHandler serves a stored image, or the variant named by the variant
+// query parameter. Names are content hashes, so a response never changes and
+// browsers may cache it forever.
+func (app *App) imageHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/images/")
+	variant := r.URL.Query().Get("variant")
+	if variant == "" {
+		variant = VariantOriginal
+	}
+	if _, ok := imageVariants[variant]; !ok && variant != VariantOriginal {
+		http.Error(w, "Unknown image variant", http.StatusBadRequest)
+		return
+	}
+	if !validImageName(name) || strings.Contains(name, "-") {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, name, err := app.loadImage(name, variant)
+	if errors.Is(err, ErrImageNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to serve image %s: %v", name, err)
+		http.Error(w, "Failed to read image", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+strings.TrimSuffix(name, filepath.Ext(name))+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	// ServeContent answers If-None-Match with 304 and handles range requests
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImageHandler(t *testing.T) {
+	app := &App{images: NewMemoryImageStore()}
+	name, err := app.images.Put([]byte("png bytes"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	app.imageHandler(rr, httptest.NewRequest("GET", imageURL(name, VariantOriginal), nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "png bytes" {
+		t.Fatalf("expected the image, got %d %q", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("expected image/png, got %q", got)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("expected an immutable cache header, got %q", got)
+	}
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	req := httptest.NewRequest("GET", imageURL(name, VariantOriginal), nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	app.imageHandler(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	app.imageHandler(rr, httptest.NewRequest("GET", "/images/../state.json", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown image, got %d", rr.Code)
+	}
+}
+
+func TestImageHandlerServesVariants(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	src.Set(0, 0, color.Black)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{images: NewMemoryImageStore()}
+	stored, err := app.storeImage(&GeneratedImage{Data: buf.Bytes(), MIMEType: "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stored without variants, as images were before variants existed
+	legacy, err := app.images.Put(append(buf.Bytes(), 0), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, variant string
+		width, height int
+	}{
+		{name: stored, variant: VariantThumb, width: 320, height: 160},
+		{name: stored, variant: VariantDisplay, width: 800, height: 400}, // Never scaled up
+		{name: legacy, variant: VariantThumb, width: 320, height: 160},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		app.imageHandler(rr, httptest.NewRequest("GET", imageURL(tt.name, tt.variant), nil))
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("%s: expected a JPEG, got %d %q", tt.variant, rr.Code, rr.Header().Get("Content-Type"))
+		}
+		decoded, err := jpeg.Decode(rr.Body)
+		if err != nil {
+			t.Fatalf("%s: invalid JPEG: %v", tt.variant, err)
+		}
+		if size := decoded.Bounds().Size(); size.X != tt.width || size.Y != tt.height {
+			t.Errorf("%s: expected %dx%d, got %v", tt.variant, tt.width, tt.height, size)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	app.imageHandler(rr, httptest.NewRequest("GET", "/images/"+stored+"?variant=huge", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown variant, got %d", rr.Code)
+	}
+}
+
+func TestPruneImagesKeepsReferencedImages(t *testing.T) {
+	images := NewFileImageStore(filepath.Join(t.TempDir(), "images"))
+	app := &App{
+		images:       images,
+		contentCache: NewContentCache(5),
+		sessions:     NewSessionManager(),
+	}
+	put := func(data string) string {
+		name, err := images.Put([]byte(data), "image/png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	cached, shown, orphan := put("cached"), put("shown"), put("orphan")
+
+	app.contentCache.Push(GameContent{BusinessName: "Cached", Image1: cached, Image2: cached})
+	app.sessions.GetOrCreate(context.Background(), "Alice", func(ctx context.Context) (*DeckBuild, error) {
+		return completedDeck(GameContent{BusinessName: "Shown", Image1: shown, Image2: shown}), nil
+	})
+
+	// Everything is too new to prune yet
+	app.pruneImages(time.Now())
+	if !images.Has(orphan) {
+		t.Fatal("expected a recently stored image to survive")
+	}
+
+	app.pruneImages(time.Now().Add(2 * imagePruneGrace))
+	if !images.Has(cached) || !images.Has(shown) {
+		t.Error("expected images of cached decks and active games to be kept")
+	}
+	if images.Has(orphan) {
+		t.Error("expected the unreferenced image to be pruned")
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 )
 
 //go:embed templates
@@ -38,6 +40,26 @@ func main() {
 		}
 	}
 
+	// Configure an optional byte budget for the cache, counting each deck's images
+	var cacheMaxBytes int64 // Default: limited by deck count only
+	if maxBytesStr := os.Getenv("CACHE_MAX_BYTES"); maxBytesStr != "" {
+		if maxBytes, err := parseByteSize(maxBytesStr); err == nil {
+			cacheMaxBytes = maxBytes
+		} else {
+			log.Printf("Invalid CACHE_MAX_BYTES value '%s', using no byte budget: %v", maxBytesStr, err)
+		}
+	}
+
+	// Configure how long a cached deck stays fresh enough to serve
+	cacheTTL := 24 * time.Hour
+	if ttlStr := os.Getenv("CACHE_TTL"); ttlStr != "" {
+		if ttl, err := time.ParseDuration(ttlStr); err == nil && ttl >= 0 {
+			cacheTTL = ttl
+		} else {
+			log.Printf("Invalid CACHE_TTL value '%s', using default: %v", ttlStr, cacheTTL)
+		}
+	}
+
 	// Configure whether to enable preloading
 	enablePreload := true // Default enabled for production
 	if preloadStr := os.Getenv("ENABLE_PRELOAD"); preloadStr != "" {
@@ -48,24 +70,184 @@ func main() {
 		}
 	}
 
-	log.Printf("Content cache configured: size=%d, preload=%t", cacheSize, enablePreload)
+	// Configure preloader concurrency and the global ceiling on concurrent generations
+	preloadWorkers := 3
+	if workersStr := os.Getenv("PRELOAD_WORKERS"); workersStr != "" {
+		if workers, err := strconv.Atoi(workersStr); err == nil && workers > 0 {
+			preloadWorkers = workers
+		} else {
+			log.Printf("Invalid PRELOAD_WORKERS value '%s', using default: %d", workersStr, preloadWorkers)
+		}
+	}
+	generationConcurrency := 4
+	if concurrencyStr := os.Getenv("GENERATION_CONCURRENCY"); concurrencyStr != "" {
+		if concurrency, err := strconv.Atoi(concurrencyStr); err == nil && concurrency > 0 {
+			generationConcurrency = concurrency
+		} else {
+			log.Printf("Invalid GENERATION_CONCURRENCY value '%s', using default: %d", concurrencyStr, generationConcurrency)
+		}
+	}
+
+	// Configure how long without consumption before the preloader drops to a trickle
+	preloadIdleTimeout := 30 * time.Minute
+	if idleStr := os.Getenv("PRELOAD_IDLE_TIMEOUT"); idleStr != "" {
+		if idle, err := time.ParseDuration(idleStr); err == nil && idle >= 0 {
+			preloadIdleTimeout = idle
+		} else {
+			log.Printf("Invalid PRELOAD_IDLE_TIMEOUT value '%s', using default: %v", idleStr, preloadIdleTimeout)
+		}
+	}
+
+	// Configure how long a presenter waits for the initial fill before generating on-demand
+	cacheWaitTimeout := 15 * time.Second
+	if waitStr := os.Getenv("CACHE_WAIT_TIMEOUT"); waitStr != "" {
+		if wait, err := time.ParseDuration(waitStr); err == nil && wait >= 0 {
+			cacheWaitTimeout = wait
+		} else {
+			log.Printf("Invalid CACHE_WAIT_TIMEOUT value '%s', using default: %v", waitStr, cacheWaitTimeout)
+		}
+	}
+
+	log.Printf("Content cache configured: size=%d, preload=%t, workers=%d, concurrency=%d, idle timeout=%v, wait timeout=%v", cacheSize, enablePreload, preloadWorkers, generationConcurrency, preloadIdleTimeout, cacheWaitTimeout)
+
+	// Configure whether image slides illustrate the business idea
+	coherentDeck := true // Default enabled so the images support the pitch
+	if coherentStr := os.Getenv("COHERENT_DECK"); coherentStr != "" {
+		if coherent, err := strconv.ParseBool(coherentStr); err == nil {
+			coherentDeck = coherent
+		} else {
+			log.Printf("Invalid COHERENT_DECK value '%s', using default: %t", coherentStr, coherentDeck)
+		}
+	}
+	log.Printf("Coherent deck mode: %t", coherentDeck)
+
+	// Configure where event state is persisted between restarts
+	dataDir := "data"
+	if dataDirStr := os.Getenv("DATA_DIR"); dataDirStr != "" {
+		dataDir = dataDirStr
+	}
+	stateFile := filepath.Join(dataDir, "state.json")
+	log.Printf("Event state persisted to %s", stateFile)
+
+	// Configure admin authentication: a credentials file takes precedence over a shared password
+	var auth *Authenticator
+	if credentialsFile := os.Getenv("ADMIN_CREDENTIALS_FILE"); credentialsFile != "" {
+		var err error
+		auth, err = LoadCredentialsFile(credentialsFile)
+		if err != nil {
+			log.Fatalf("failed to load admin credentials: %v", err)
+		}
+		log.Printf("Admin credentials loaded from %s", credentialsFile)
+	} else if adminPassword := os.Getenv("ADMIN_PASSWORD"); adminPassword != "" {
+		auth = NewPasswordAuthenticator(adminPassword)
+	} else {
+		// Never leave the admin panel open; generate a one-off password instead
+		adminPassword = newID()[:12]
+		auth = NewPasswordAuthenticator(adminPassword)
+		log.Printf("Warning: ADMIN_PASSWORD not set. Generated admin password for this run: %s", adminPassword)
+	}
 
 	templates := template.Must(template.ParseFS(templateFS, "templates/*.html"))
 
-	generator, err := NewAiGenerator(googleAPIKey)
+	// Configure where the editable word lists and prompt instructions live
+	promptPackFile := filepath.Join(dataDir, "prompts.json")
+	if promptPackStr := os.Getenv("PROMPT_PACK_FILE"); promptPackStr != "" {
+		promptPackFile = promptPackStr
+	}
+	prompts, err := NewPromptStore(promptPackFile)
+	if err != nil {
+		log.Fatalf("failed to load prompt pack: %v", err)
+	}
+	log.Printf("Prompt pack loaded from %s (built-in pack used until the file exists)", promptPackFile)
+
+	aiGenerator, err := NewAiGenerator(googleAPIKey, prompts)
 	if err != nil {
 		log.Fatalf("failed to create AI generator: %v", err)
 	}
 
+	// Configure moderation of generated content: a blocklist file replaces the built-in list
+	blocklist := NewBlocklist(defaultBlocklist)
+	if blocklistFile := os.Getenv("MODERATION_BLOCKLIST_FILE"); blocklistFile != "" {
+		blocklist, err = LoadBlocklistFile(blocklistFile)
+		if err != nil {
+			log.Fatalf("failed to load moderation blocklist: %v", err)
+		}
+	}
+	moderationAttempts := defaultModerationAttempts
+	if attemptsStr := os.Getenv("MODERATION_MAX_ATTEMPTS"); attemptsStr != "" {
+		if attempts, err := strconv.Atoi(attemptsStr); err == nil && attempts > 0 {
+			moderationAttempts = attempts
+		} else {
+			log.Printf("Invalid MODERATION_MAX_ATTEMPTS value '%s', using default: %d", attemptsStr, moderationAttempts)
+		}
+	}
+	rejectionLog := filepath.Join(dataDir, "rejections.jsonl")
+	generator := newModeratedGenerator(aiGenerator, blocklist, moderationAttempts, NewRejectionLog(rejectionLog))
+	log.Printf("Moderation: %d blocked terms, up to %d attempts per stage, rejections logged to %s", blocklist.Len(), moderationAttempts, rejectionLog)
+
 	app := &App{
-		templates:    templates,
-		giphyAPIKey:  giphyAPIKey,
-		googleAPIKey: googleAPIKey,
-		generator:    generator,
-		usedGifs:     make(map[string]bool),
-		contentCache: NewContentCache(cacheSize),
+		templates:          templates,
+		giphyAPIKey:        giphyAPIKey,
+		googleAPIKey:       googleAPIKey,
+		generator:          generator,
+		prompts:            prompts,
+		coherentDeck:       coherentDeck,
+		preloadWorkers:     preloadWorkers,
+		preloadIdleTimeout: preloadIdleTimeout,
+		cacheWaitTimeout:   cacheWaitTimeout,
+		usedGifs:           make(map[string]bool),
+		contentCache:       NewContentCache(cacheSize),
+		images:             NewFileImageStore(filepath.Join(dataDir, "images")),
+		sessions:           NewSessionManager(),
+		events:             NewEventBroker(),
+		auth:               auth,
+		stateStore:         NewFileStateStore(stateFile),
 	}
 
+	app.scheduler = NewGenerationScheduler(generationConcurrency, app.contentCache, app.generateGameContent)
+
+	// Push cache and game changes to connected pages
+	app.contentCache.OnChange(func() {
+		app.publish("cache", app.cacheEvent())
+	})
+	app.sessions.OnChange(func(action string, session GameSession) {
+		app.publish("game", app.gameEvent(action, session))
+		if action == "failed" {
+			// The talk has already started, so fill in the missing slides
+			// rather than leave them blank
+			go app.repairDeck(session.ID)
+		}
+	})
+
+	if err := app.loadState(); err != nil {
+		log.Fatalf("failed to load event state: %v", err)
+	}
+
+	if cacheMaxBytes > 0 {
+		app.contentCache.SetByteBudget(cacheMaxBytes, app.deckSize)
+		log.Printf("Content cache byte budget: %s", formatBytes(cacheMaxBytes))
+	}
+
+	app.contentCache.SetTTL(cacheTTL)
+
+	// Warm-start the content cache from the last snapshot
+	if err := app.contentCache.EnablePersistence(filepath.Join(dataDir, "cache.json"), app.images); err != nil {
+		log.Printf("Failed to restore content cache, starting empty: %v", err)
+	}
+	log.Printf("Restored %d cached content items from %s", app.contentCache.Size(), dataDir)
+
+	if cacheTTL > 0 {
+		go app.runCacheSweeper(cacheTTL)
+		log.Printf("Cached decks expire after %v", cacheTTL)
+	}
+
+	// Clean up images left behind by decks that are gone
+	app.pruneImages(time.Now())
+	go app.runImagePruner()
+
+	// Pick up prompt pack edits made on disk without a restart
+	go prompts.Watch(promptPackPollInterval)
+
 	// Start background content preloader only if enabled
 	if enablePreload {
 		app.StartContentPreloader(context.Background())
@@ -74,14 +256,31 @@ func main() {
 		log.Println("Content preloader disabled")
 	}
 
+	// Public, read-only pages
 	http.HandleFunc("/", app.indexHandler)
-	http.HandleFunc("/admin", app.adminHandler)
-	http.HandleFunc("/participants", app.participantsHandler)
-	http.HandleFunc("/remove-participant", app.removeParticipantHandler)
-	http.HandleFunc("/next-participant", app.nextParticipantHandler)
-	http.HandleFunc("/preload-cache", app.preloadCacheHandler)
 	http.HandleFunc("/game/", app.gameHandler)
 	http.HandleFunc("/api/game-data/", app.gameDataHandler)
+	http.HandleFunc("/api/game-stream/", app.gameStreamHandler)
+	http.HandleFunc("/api/game-state/", app.gameStateHandler)
+	http.HandleFunc("/images/", app.imageHandler)
+	http.HandleFunc("/events", app.eventsHandler)
+	http.HandleFunc("/login", app.loginHandler)
+	http.HandleFunc("/logout", app.logoutHandler)
+
+	// Admin pages and everything that changes event state
+	http.HandleFunc("/admin", app.requireAdmin(app.adminHandler))
+	http.HandleFunc("/participants", app.requireAdmin(app.participantsHandler))
+	http.HandleFunc("/theme", app.requireAdmin(app.themeHandler))
+	http.HandleFunc("/remove-participant", app.requireAdmin(app.removeParticipantHandler))
+	http.HandleFunc("/next-participant", app.requireAdmin(app.nextParticipantHandler))
+	http.HandleFunc("/preload-cache", app.requireAdmin(app.preloadCacheHandler))
+	http.HandleFunc("/decks", app.requireAdmin(app.decksHandler))
+	http.HandleFunc("/deck-control", app.requireAdmin(app.deckControlHandler))
+	http.HandleFunc("/review-mode", app.requireAdmin(app.reviewModeHandler))
+	http.HandleFunc("/game-control", app.requireAdmin(app.gameControlHandler))
+	http.HandleFunc("/reroll-slide", app.requireAdmin(app.rerollSlideHandler))
+	http.HandleFunc("/prompts", app.requireAdmin(app.promptsHandler))
+	http.HandleFunc("/glossary", app.requireAdmin(app.glossaryHandler))
 
 	// Serve static files from embedded filesystem
 	staticContent, err := fs.Sub(staticFS, "static")
@@ -91,7 +290,7 @@ func main() {
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(staticContent))))
 
 	fmt.Println("Starting server on :8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+	if err := http.ListenAndServe(":8080", csrfProtect(http.DefaultServeMux)); err != nil {
 		fmt.Printf("Error starting server: %s\n", err)
 	}
 }
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"google.golang.org/genai"
+)
+
+// ErrContentRejected is returned when generated content fails moderation
+var ErrContentRejected = errors.New("content rejected by moderation")
+
+// defaultModerationAttempts bounds how often a rejected stage is regenerated
+const defaultModerationAttempts = 3
+
+// defaultBlocklist is used when no blocklist file is configured
+var defaultBlocklist = []string{
+	"suicide", "self-harm", "rape", "porn", "nude", "naked", "nazi", "hitler",
+	"genocide", "terrorist", "cocaine", "heroin", "slur", "racist",
+}
+
+// safetySettings are sent with every Gemini request. A talk at a company
+// event is no place for borderline content, so the thresholds are strict.
+var safetySettings = []*genai.SafetySetting{
+	{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+	{Category: genai.HarmCategoryHateSpeech, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+	{Category: genai.HarmCategorySexuallyExplicit, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+	{Category: genai.HarmCategoryDangerousContent, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+}
+
+// checkResponseSafety rejects a Gemini response that was blocked or rated
+// as possibly harmful
+func checkResponseSafety(resp *genai.GenerateContentResponse) error {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return fmt.Errorf("%w: prompt blocked (%s)", ErrContentRejected, resp.PromptFeedback.BlockReason)
+	}
+	if len(resp.Candidates) == 0 {
+		return fmt.Errorf("%w: no candidates returned", ErrContentRejected)
+	}
+
+	candidate := resp.Candidates[0]
+	switch candidate.FinishReason {
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent, genai.FinishReasonSPII:
+		return fmt.Errorf("%w: response stopped (%s)", ErrContentRejected, candidate.FinishReason)
+	}
+	for _, rating := range candidate.SafetyRatings {
+		if rating.Blocked || rating.Probability == genai.HarmProbabilityMedium || rating.Probability == genai.HarmProbabilityHigh {
+			return fmt.Errorf("%w: rated %s for %s", ErrContentRejected, rating.Probability, rating.Category)
+		}
+	}
+	return nil
+}
+
+// Blocklist matches whole words and phrases, ignoring case and punctuation,
+// so "Scunthorpe" does not trip an entry for a word inside it
+type Blocklist struct {
+	terms []string // Normalised, each padded with spaces
+}
+
+// NewBlocklist creates a blocklist from the given words and phrases
+func NewBlocklist(terms []string) *Blocklist {
+	b := &Blocklist{}
+	for _, term := range terms {
+		if normalised := normaliseForBlocklist(term); normalised != "" {
+			b.terms = append(b.terms, " "+normalised+" ")
+		}
+	}
+	return b
+}
+
+// LoadBlocklistFile reads a blocklist with one word or phrase per line.
+// Blank lines and lines starting with # are ignored.
+func LoadBlocklistFile(path string) (*Blocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blocklist file: %w", err)
+	}
+	defer file.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist file: %w", err)
+	}
+	return NewBlocklist(terms), nil
+}
+
+// Match returns the first blocked term found in text, if any
+func (b *Blocklist) Match(text string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	padded := " " + normaliseForBlocklist(text) + " "
+	for _, term := range b.terms {
+		if strings.Contains(padded, term) {
+			return strings.TrimSpace(term), true
+		}
+	}
+	return "", false
+}
+
+// Len returns the number of blocked terms
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.terms)
+}
+
+// normaliseForBlocklist lowercases text and turns every run of punctuation
+// and whitespace into a single space
+func normaliseForBlocklist(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// Rejection is one piece of generated content that failed moderation
+type Rejection struct {
+	Time    time.Time `json:"time"`
+	Stage   string    `json:"stage"` // businessIdea, imagePrompt or image
+	Reason  string    `json:"reason"`
+	Content string    `json:"content,omitempty"`
+}
+
+// RejectionLog appends rejections to a JSON Lines file for later review
+type RejectionLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewRejectionLog creates a rejection log writing to path
+func NewRejectionLog(path string) *RejectionLog {
+	return &RejectionLog{path: path}
+}
+
+// Record logs a rejection and appends it to the file. A nil log only logs.
+func (l *RejectionLog) Record(rejection Rejection) {
+	log.Printf("Moderation rejected %s: %s", rejection.Stage, rejection.Reason)
+	if l == nil {
+		return
+	}
+
+	data, err := json.Marshal(rejection)
+	if err != nil {
+		log.Printf("Failed to encode rejection: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		log.Printf("Failed to record rejection: %v", err)
+		return
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Printf("Failed to record rejection: %v", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to record rejection: %v", err)
+	}
+}
+
+// moderatedGenerator checks everything the wrapped generator produces and
+// regenerates content that fails, up to a bounded number of attempts
+type moderatedGenerator struct {
+	Generator
+	blocklist   *Blocklist
+	maxAttempts int
+	rejections  *RejectionLog
+}
+
+var _ Generator = (*moderatedGenerator)(nil)
+
+// newModeratedGenerator wraps generator with moderation
+func newModeratedGenerator(generator Generator, blocklist *Blocklist, maxAttempts int, rejections *RejectionLog) *moderatedGenerator {
+	return &moderatedGenerator{
+		Generator:   generator,
+		blocklist:   blocklist,
+		maxAttempts: max(maxAttempts, 1),
+		rejections:  rejections,
+	}
+}
+
+// moderate runs generate until its content passes check. Errors other than
+// rejections are returned straight away.
+func (g *moderatedGenerator) moderate(stage string, generate func() (string, error), check func(content string) error) error {
+	var lastErr error
+	for attempt := 1; attempt <= g.maxAttempts; attempt++ {
+		content, err := generate()
+		if err != nil && !errors.Is(err, ErrContentRejected) {
+			return err
+		}
+		if err == nil {
+			if err = check(content); err == nil {
+				return nil
+			}
+		}
+
+		lastErr = err
+		g.rejections.Record(Rejection{
+			Time:    time.Now(),
+			Stage:   stage,
+			Reason:  fmt.Sprintf("attempt %d/%d: %v", attempt, g.maxAttempts, err),
+			Content: content,
+		})
+	}
+	return fmt.Errorf("no acceptable %s after %d attempts: %w", stage, g.maxAttempts, lastErr)
+}
+
+// checkText returns a check rejecting text that contains a blocked term or
+// anything the company glossary says not to mention
+func (g *moderatedGenerator) checkText(opts GenerationOptions) func(text string) error {
+	doNotMention := NewBlocklist(opts.Glossary.DoNotMention)
+	return func(text string) error {
+		if term, ok := g.blocklist.Match(text); ok {
+			return fmt.Errorf("%w: contains blocked term %q", ErrContentRejected, term)
+		}
+		if term, ok := doNotMention.Match(text); ok {
+			return fmt.Errorf("%w: mentions %q from the glossary's do-not-mention list", ErrContentRejected, term)
+		}
+		return nil
+	}
+}
+
+func (g *moderatedGenerator) GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error) {
+	var businessName, slogan string
+	err := g.moderate("businessIdea", func() (string, error) {
+		var err error
+		businessName, slogan, err = g.Generator.GenerateBusinessIdea(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		return businessName + " - " + slogan, nil
+	}, g.checkText(opts))
+	if err != nil {
+		return "", "", err
+	}
+	return businessName, slogan, nil
+}
+
+func (g *moderatedGenerator) GenerateImagePrompt(ctx context.Context, brief *DeckBrief, opts GenerationOptions) (string, error) {
+	var prompt string
+	err := g.moderate("imagePrompt", func() (string, error) {
+		var err error
+		prompt, err = g.Generator.GenerateImagePrompt(ctx, brief, opts)
+		return prompt, err
+	}, g.checkText(opts))
+	if err != nil {
+		return "", err
+	}
+	return prompt, nil
+}
+
+func (g *moderatedGenerator) GenerateImage(ctx context.Context, prompt string) (*GeneratedImage, error) {
+	// The image itself is judged by Imagen's safety filter, so a rejection
+	// here means it filtered every image it made
+	var image *GeneratedImage
+	err := g.moderate("image", func() (string, error) {
+		var err error
+		image, err = g.Generator.GenerateImage(ctx, prompt)
+		return prompt, err
+	}, func(string) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestBlocklistMatchesWholeWords(t *testing.T) {
+	blocklist := NewBlocklist([]string{"ass", "hot dog"})
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Kick-ASS Consulting", true},
+		{"Classy Assistants", false},
+		{"Hot  dog, delivered!", true},
+		{"Hotdogs for Dogs", false},
+	}
+	for _, tt := range tests {
+		if _, got := blocklist.Match(tt.text); got != tt.want {
+			t.Errorf("Match(%q) = %t, want %t", tt.text, got, tt.want)
+		}
+	}
+}
+
+// scriptedIdeaGenerator returns a fixed sequence of business ideas
+type scriptedIdeaGenerator struct {
+	MockGenerator
+	ideas []string
+	errs  []error
+	calls int
+}
+
+func (g *scriptedIdeaGenerator) GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error) {
+	i := g.calls
+	g.calls++
+	if i < len(g.errs) && g.errs[i] != nil {
+		return "", "", g.errs[i]
+	}
+	return g.ideas[i], "A slogan", nil
+}
+
+func TestModeratedGeneratorRegeneratesRejectedContent(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "rejections.jsonl")
+	inner := &scriptedIdeaGenerator{
+		ideas: []string{"", "Nazi Noodles", "Polite Pasta"},
+		errs:  []error{ErrContentRejected},
+	}
+	generator := newModeratedGenerator(inner, NewBlocklist(defaultBlocklist), 3, NewRejectionLog(logPath))
+
+	name, _, err := generator.GenerateBusinessIdea(context.Background(), GenerationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "Polite Pasta" || inner.calls != 3 {
+		t.Errorf("expected the third idea, got %q after %d calls", name, inner.calls)
+	}
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var rejections []Rejection
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rejection Rejection
+		if err := json.Unmarshal(scanner.Bytes(), &rejection); err != nil {
+			t.Fatal(err)
+		}
+		rejections = append(rejections, rejection)
+	}
+	if len(rejections) != 2 || rejections[1].Stage != "businessIdea" || rejections[1].Content != "Nazi Noodles - A slogan" {
+		t.Errorf("expected both rejections to be logged, got %+v", rejections)
+	}
+}
+
+func TestModeratedGeneratorGivesUp(t *testing.T) {
+	inner := &scriptedIdeaGenerator{ideas: []string{"Hitler Hats", "Hitler Hats"}}
+	generator := newModeratedGenerator(inner, NewBlocklist(defaultBlocklist), 2, nil)
+	if _, _, err := generator.GenerateBusinessIdea(context.Background(), GenerationOptions{}); !errors.Is(err, ErrContentRejected) {
+		t.Errorf("expected a rejection after the last attempt, got %v", err)
+	}
+
+	// Failures unrelated to moderation are not retried
+	apiErr := errors.New("quota exceeded")
+	inner = &scriptedIdeaGenerator{errs: []error{apiErr}}
+	generator = newModeratedGenerator(inner, NewBlocklist(defaultBlocklist), 3, nil)
+	if _, _, err := generator.GenerateBusinessIdea(context.Background(), GenerationOptions{}); !errors.Is(err, apiErr) || inner.calls != 1 {
+		t.Errorf("expected the API error after one call, got %v after %d calls", err, inner.calls)
+	}
+}
+
+func TestCheckResponseSafety(t *testing.T) {
+	tests := []struct {
+		name   string
+		resp   *genai.GenerateContentResponse
+		reject bool
+	}{
+		{name: "clean", resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+			FinishReason:  genai.FinishReasonStop,
+			SafetyRatings: []*genai.SafetyRating{{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityNegligible}},
+		}}}},
+		{name: "prompt blocked", reject: true, resp: &genai.GenerateContentResponse{
+			PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonSafety},
+		}},
+		{name: "stopped for safety", reject: true, resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+			FinishReason: genai.FinishReasonSafety,
+		}}}},
+		{name: "medium rating", reject: true, resp: &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+			FinishReason:  genai.FinishReasonStop,
+			SafetyRatings: []*genai.SafetyRating{{Category: genai.HarmCategoryHateSpeech, Probability: genai.HarmProbabilityMedium}},
+		}}}},
+	}
+	for _, tt := range tests {
+		err := checkResponseSafety(tt.resp)
+		if rejected := errors.Is(err, ErrContentRejected); rejected != tt.reject {
+			t.Errorf("%s: expected rejected=%t, got %v", tt.name, tt.reject, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// promptPackPollInterval is how often the prompt pack file is checked for edits
+const promptPackPollInterval = 5 * time.Second
+
+//go:embed prompts/default.json
+var defaultPromptPackJSON []byte
+
+// defaultPromptPack is used until a prompt pack file is saved
+var defaultPromptPack = mustParsePromptPack(defaultPromptPackJSON)
+
+// PromptPack holds the word lists and instructions the generator builds its
+// requests from, so the game can be tailored without a code change
+type PromptPack struct {
+	// Business idea fields, one of each picked at random
+	BusinessTypes   []string `json:"businessTypes"`
+	TargetAudiences []string `json:"targetAudiences"`
+	AbsurdProblems  []string `json:"absurdProblems"`
+	// May use {maxNameLength} and {maxSloganLength}
+	BusinessIdeaInstructions string `json:"businessIdeaInstructions"`
+
+	// Image prompt fields, one of each picked at random
+	CharacterAges []string `json:"characterAges"`
+	Settings      []string `json:"settings"`
+	AbsurdTwists  []string `json:"absurdTwists"`
+	VisualStyle   string   `json:"visualStyle"`
+
+	ImagePromptInstructions         string `json:"imagePromptInstructions"`         // For random scenes
+	CoherentImagePromptInstructions string `json:"coherentImagePromptInstructions"` // For scenes supporting the pitch
+	ProblemSceneGoal                string `json:"problemSceneGoal"`
+	ProductSceneGoal                string `json:"productSceneGoal"`
+}
+
+// parsePromptPack decodes and validates a JSON prompt pack
+func parsePromptPack(data []byte) (*PromptPack, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var pack PromptPack
+	if err := decoder.Decode(&pack); err != nil {
+		return nil, fmt.Errorf("invalid prompt pack JSON: %w", err)
+	}
+	if err := pack.Validate(); err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+func mustParsePromptPack(data []byte) *PromptPack {
+	pack, err := parsePromptPack(data)
+	if err != nil {
+		panic(fmt.Sprintf("embedded prompt pack: %v", err))
+	}
+	return pack
+}
+
+// Validate checks that every list has entries and every instruction is set
+func (p *PromptPack) Validate() error {
+	lists := []struct {
+		name  string
+		items []string
+	}{
+		{"businessTypes", p.BusinessTypes},
+		{"targetAudiences", p.TargetAudiences},
+		{"absurdProblems", p.AbsurdProblems},
+		{"characterAges", p.CharacterAges},
+		{"settings", p.Settings},
+		{"absurdTwists", p.AbsurdTwists},
+	}
+	for _, list := range lists {
+		if len(list.items) == 0 {
+			return fmt.Errorf("%s must have at least one entry", list.name)
+		}
+		for i, item := range list.items {
+			if strings.TrimSpace(item) == "" {
+				return fmt.Errorf("%s entry %d is blank", list.name, i+1)
+			}
+		}
+	}
+
+	texts := []struct {
+		name  string
+		value string
+	}{
+		{"businessIdeaInstructions", p.BusinessIdeaInstructions},
+		{"visualStyle", p.VisualStyle},
+		{"imagePromptInstructions", p.ImagePromptInstructions},
+		{"coherentImagePromptInstructions", p.CoherentImagePromptInstructions},
+		{"problemSceneGoal", p.ProblemSceneGoal},
+		{"productSceneGoal", p.ProductSceneGoal},
+	}
+	for _, text := range texts {
+		if strings.TrimSpace(text.value) == "" {
+			return fmt.Errorf("%s must not be empty", text.name)
+		}
+	}
+	return nil
+}
+
+// businessIdeaInstructions fills the length limits into the instructions
+func (p *PromptPack) businessIdeaInstructions() string {
+	return strings.NewReplacer(
+		"{maxNameLength}", strconv.Itoa(maxBusinessNameLength),
+		"{maxSloganLength}", strconv.Itoa(maxSloganLength),
+	).Replace(p.BusinessIdeaInstructions)
+}
+
+// PromptStore holds the current prompt pack, reloading it when its file
+// changes on disk and saving edits made from the admin page
+type PromptStore struct {
+	mu      sync.RWMutex
+	pack    *PromptPack
+	path    string
+	modTime time.Time // Of the file the pack was loaded from
+}
+
+// NewPromptStore loads the prompt pack at path, falling back to the built-in
+// pack if the file does not exist yet
+func NewPromptStore(path string) (*PromptStore, error) {
+	s := &PromptStore{pack: defaultPromptPack, path: path}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Pack returns the current prompt pack. A nil store returns the built-in pack.
+func (s *PromptStore) Pack() *PromptPack {
+	if s == nil {
+		return defaultPromptPack
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pack
+}
+
+// Reload reads the prompt pack file again if it changed since it was last
+// loaded, reporting whether a new pack was loaded. An invalid file leaves the
+// current pack in place.
+func (s *PromptStore) Reload() (bool, error) {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat prompt pack: %w", err)
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read prompt pack: %w", err)
+	}
+	pack, err := parsePromptPack(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modTime = info.ModTime() // Don't retry a broken file until it changes again
+	if err != nil {
+		return false, fmt.Errorf("prompt pack %s: %w", s.path, err)
+	}
+	s.pack = pack
+	return true, nil
+}
+
+// Save validates a JSON prompt pack, writes it to the pack file and makes it
+// current
+func (s *PromptStore) Save(data []byte) error {
+	pack, err := parsePromptPack(data)
+	if err != nil {
+		return err
+	}
+	formatted, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt pack: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeFileAtomic(s.path, append(formatted, '\n')); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	s.pack = pack
+	return nil
+}
+
+// JSON returns the current prompt pack formatted for editing
+func (s *PromptStore) JSON() string {
+	data, err := json.MarshalIndent(s.Pack(), "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode prompt pack: %v", err)
+		return ""
+	}
+	return string(data)
+}
+
+// Path returns the prompt pack file
+func (s *PromptStore) Path() string {
+	return s.path
+}
+
+// Watch reloads the prompt pack whenever its file changes. It never returns.
+func (s *PromptStore) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		reloaded, err := s.Reload()
+		if err != nil {
+			log.Printf("Keeping the current prompt pack: %v", err)
+			continue
+		}
+		if reloaded {
+			log.Printf("Reloaded prompt pack from %s", s.path)
+		}
+	}
+}
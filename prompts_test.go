@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePromptPack(t *testing.T) {
+	if err := defaultPromptPack.Validate(); err != nil {
+		t.Fatalf("built-in prompt pack is invalid: %v", err)
+	}
+	if got := defaultPromptPack.businessIdeaInstructions(); strings.Contains(got, "{") {
+		t.Errorf("expected the length limits to be filled in, got %q", got)
+	}
+
+	tests := []struct {
+		name string
+		edit func(pack *PromptPack)
+	}{
+		{"empty list", func(pack *PromptPack) { pack.Settings = nil }},
+		{"blank entry", func(pack *PromptPack) { pack.AbsurdTwists = []string{"a twist", " "} }},
+		{"blank instructions", func(pack *PromptPack) { pack.ProductSceneGoal = "" }},
+	}
+	for _, tt := range tests {
+		pack := *defaultPromptPack
+		tt.edit(&pack)
+		if err := pack.Validate(); err == nil {
+			t.Errorf("%s: expected the pack to be rejected", tt.name)
+		}
+	}
+
+	typo := strings.Replace(string(defaultPromptPackJSON), `"businessTypes"`, `"businessTypez"`, 1)
+	if _, err := parsePromptPack([]byte(typo)); err == nil {
+		t.Error("expected a misspelled field to be rejected")
+	}
+}
+
+func TestPromptStoreReloadsEditedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	store, err := NewPromptStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Pack() != defaultPromptPack {
+		t.Fatal("expected the built-in pack before the file exists")
+	}
+
+	write := func(json string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(json), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+
+	edited := strings.Replace(string(defaultPromptPackJSON), `"visualStyle": "photorealistic"`, `"visualStyle": "claymation"`, 1)
+	write(edited, now.Add(time.Second))
+	if reloaded, err := store.Reload(); !reloaded || err != nil {
+		t.Fatalf("expected the edit to load, got %t, %v", reloaded, err)
+	}
+	if got := store.Pack().VisualStyle; got != "claymation" {
+		t.Errorf("expected the edited visual style, got %q", got)
+	}
+
+	write(`{"businessTypes": []}`, now.Add(2*time.Second))
+	if _, err := store.Reload(); err == nil {
+		t.Error("expected an invalid edit to be reported")
+	}
+	if got := store.Pack().VisualStyle; got != "claymation" {
+		t.Errorf("expected the last valid pack to stay in use, got %q", got)
+	}
+
+	if err := store.Save(defaultPromptPackJSON); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	if reloaded, _ := store.Reload(); reloaded {
+		t.Error("expected a saved pack not to be reloaded again")
+	}
+	if got := store.Pack().VisualStyle; got != "photorealistic" {
+		t.Errorf("expected the saved pack, got %q", got)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// GenerationPriority orders deck generation when slots are scarce
+type GenerationPriority int
+
+const (
+	PriorityPreload GenerationPriority = iota // Background cache refills
+	PriorityLive                              // A presenter is waiting on the deck
+)
+
+// generateFunc generates one deck, calling onProgress (if set) as parts land
+type generateFunc func(ctx context.Context, onProgress func(GameContent)) (*GameContent, error)
+
+// GenerationScheduler is the single place decks get generated. It keeps the
+// number of concurrent generations within a global limit, lets live presenter
+// requests jump ahead of the preloader, and hands every finished deck to the
+// longest-waiting presenter before anything goes into the cache.
+type GenerationScheduler struct {
+	generate generateFunc
+	cache    *ContentCache
+
+	mu       sync.Mutex
+	slots    int
+	running  int
+	queued   map[GenerationPriority][]chan struct{} // Callers waiting for a slot, oldest first
+	waiters  []chan deckResult                      // Presenters waiting for a deck, oldest first
+	liveJobs int                                    // Live generations queued or running
+}
+
+// errAwaitReview tells a waiting presenter to wait for a deck the host has
+// approved instead
+var errAwaitReview = errors.New("waiting for an approved deck")
+
+type deckResult struct {
+	deck *DeckBuild
+	err  error
+}
+
+// SchedulerStats is a snapshot of the scheduler for the admin page
+type SchedulerStats struct {
+	Slots         int
+	Running       int
+	QueuedLive    int
+	QueuedPreload int
+	Waiting       int
+}
+
+// NewGenerationScheduler creates a scheduler that runs at most slots
+// generations at once and puts unclaimed decks into cache
+func NewGenerationScheduler(slots int, cache *ContentCache, generate generateFunc) *GenerationScheduler {
+	return &GenerationScheduler{
+		generate: generate,
+		cache:    cache,
+		slots:    max(slots, 1),
+		queued:   make(map[GenerationPriority][]chan struct{}),
+	}
+}
+
+// Produce generates one deck at the given priority. The deck goes to the
+// longest-waiting presenter if there is one, otherwise into the cache.
+func (s *GenerationScheduler) Produce(ctx context.Context, priority GenerationPriority) error {
+	content, err := s.run(ctx, priority, nil)
+	if err != nil {
+		return err
+	}
+	s.deliver(content)
+	return nil
+}
+
+// Await returns a deck for a presenter: from the cache if one is ready,
+// otherwise the first deck any generation finishes, or a live generation as
+// soon as its pitch is ready. The returned deck always has its pitch; its
+// images may still be rendering. A live generation is started for each
+// waiting presenter not already covered by one, so concurrent waiters never
+// start more generations than they need.
+func (s *GenerationScheduler) Await(ctx context.Context) (*DeckBuild, error) {
+	if s.cache.ReviewRequired() {
+		return s.awaitApproved(ctx)
+	}
+	if content := s.cache.Pop(); content != nil {
+		return completedDeck(*content), nil
+	}
+
+	result := make(chan deckResult, 1)
+
+	s.mu.Lock()
+	s.waiters = append(s.waiters, result)
+	uncovered := len(s.waiters) > s.liveJobs
+	if uncovered {
+		s.liveJobs++
+	}
+	s.mu.Unlock()
+
+	if uncovered {
+		// Not tied to ctx: if this presenter gives up, the deck still goes to
+		// the next one or into the cache
+		go s.produceLive()
+	}
+
+	select {
+	case r := <-result:
+		if errors.Is(r.err, errAwaitReview) {
+			return s.awaitApproved(ctx)
+		}
+		// Decks from deliver and commit bypass Pop, so count them here, once
+		// they have really reached a presenter
+		if r.deck != nil {
+			s.cache.RecordConsumed(time.Now())
+		}
+		return r.deck, r.err
+	case <-ctx.Done():
+		if s.removeWaiter(result) {
+			return nil, ctx.Err()
+		}
+		// A deck was handed over just as we gave up; don't waste it
+		if r := <-result; r.deck != nil {
+			go s.redeliver(r.deck)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// awaitApproved waits for the host to approve a cached deck
+func (s *GenerationScheduler) awaitApproved(ctx context.Context) (*DeckBuild, error) {
+	content, err := s.cache.PopWait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return completedDeck(*content), nil
+}
+
+// AwaitReview sends every waiting presenter to wait for a deck the host has
+// approved. Call it when review mode is turned on.
+func (s *GenerationScheduler) AwaitReview() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	waiters := s.waiters
+	s.waiters = nil
+	s.mu.Unlock()
+
+	for _, waiter := range waiters {
+		waiter <- deckResult{err: errAwaitReview}
+	}
+}
+
+// Stats returns a snapshot of the scheduler's load
+func (s *GenerationScheduler) Stats() SchedulerStats {
+	if s == nil {
+		return SchedulerStats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerStats{
+		Slots:         s.slots,
+		Running:       s.running,
+		QueuedLive:    len(s.queued[PriorityLive]),
+		QueuedPreload: len(s.queued[PriorityPreload]),
+		Waiting:       len(s.waiters),
+	}
+}
+
+func (s *GenerationScheduler) produceLive() {
+	deck := newDeckBuild()
+	committed, stale := false, false
+	// Hand the deck over as soon as its pitch is ready, so the talk can start
+	// while the images are still rendering
+	onProgress := func(content GameContent) {
+		deck.update(content)
+		if committed || stale || content.BusinessName == "" {
+			return
+		}
+		if s.acceptable(content) {
+			committed = s.commit(deck)
+			return
+		}
+		// The theme or glossary changed since this deck started, so it can't
+		// go to the waiter; another job takes over covering them
+		stale = true
+		s.mu.Lock()
+		s.liveJobs--
+		s.mu.Unlock()
+		s.coverWaiters()
+	}
+
+	content, err := s.run(context.Background(), PriorityLive, onProgress)
+	deck.finish(content, err)
+	if committed {
+		if err != nil {
+			log.Printf("Live content generation failed after its pitch was shown: %v", err)
+		}
+		return
+	}
+	if stale {
+		if err == nil {
+			s.deliver(content)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	s.liveJobs--
+	if err != nil {
+		// Fail the newest waiter this job was covering; older ones keep their place
+		if len(s.waiters) > s.liveJobs {
+			last := len(s.waiters) - 1
+			waiter := s.waiters[last]
+			s.waiters = s.waiters[:last]
+			waiter <- deckResult{err: err}
+		}
+		s.mu.Unlock()
+		log.Printf("Live content generation failed: %v", err)
+		return
+	}
+	s.mu.Unlock()
+
+	s.deliver(content)
+}
+
+// commit hands a live deck that is still generating to the longest-waiting
+// presenter. It reports false if nobody is waiting any more, or if the host
+// has to review the deck first.
+func (s *GenerationScheduler) commit(deck *DeckBuild) bool {
+	if s.cache.ReviewRequired() {
+		return false
+	}
+	s.mu.Lock()
+	if len(s.waiters) == 0 {
+		s.mu.Unlock()
+		return false
+	}
+	waiter := s.waiters[0]
+	s.waiters = s.waiters[1:]
+	s.liveJobs-- // This job no longer covers a waiter
+	s.mu.Unlock()
+
+	waiter <- deckResult{deck: deck}
+	return true
+}
+
+// redeliver passes on a deck whose presenter gave up once it has finished
+func (s *GenerationScheduler) redeliver(deck *DeckBuild) {
+	state, _ := deck.Wait(context.Background())
+	if state.Err == nil {
+		s.deliver(&state.Content)
+	}
+}
+
+// RunLive runs fn, such as regenerating one slide, once a slot is free. It
+// counts against the generation limit like a deck, queued at live priority.
+func (s *GenerationScheduler) RunLive(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := s.acquire(ctx, PriorityLive); err != nil {
+		return err
+	}
+	defer s.release()
+	return fn(ctx)
+}
+
+// run generates a deck once a slot is free
+func (s *GenerationScheduler) run(ctx context.Context, priority GenerationPriority, onProgress func(GameContent)) (*GameContent, error) {
+	if err := s.acquire(ctx, priority); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.generate(ctx, onProgress)
+}
+
+// deliver hands a finished deck to the longest-waiting presenter, or caches it.
+// A deck the current theme or glossary rules out goes to the cache, which
+// drops it, and in review mode every deck goes to the cache to await approval.
+func (s *GenerationScheduler) deliver(content *GameContent) {
+	if !s.acceptable(*content) {
+		s.cache.Push(*content)
+		s.coverWaiters()
+		return
+	}
+	if s.cache.ReviewRequired() {
+		s.cache.Push(*content)
+		s.AwaitReview()
+		return
+	}
+
+	s.mu.Lock()
+	if len(s.waiters) > 0 {
+		waiter := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		s.mu.Unlock()
+		waiter <- deckResult{deck: completedDeck(*content)}
+		return
+	}
+	s.mu.Unlock()
+
+	s.cache.Push(*content)
+}
+
+// acceptable reports whether a deck fits the current event theme and glossary
+func (s *GenerationScheduler) acceptable(content GameContent) bool {
+	return s.cache.Accepts(content)
+}
+
+// coverWaiters starts a live generation for each waiting presenter that no
+// queued or running live generation covers
+func (s *GenerationScheduler) coverWaiters() {
+	s.mu.Lock()
+	uncovered := len(s.waiters) - s.liveJobs
+	if uncovered > 0 {
+		s.liveJobs += uncovered
+	}
+	s.mu.Unlock()
+
+	for range uncovered {
+		go s.produceLive()
+	}
+}
+
+func (s *GenerationScheduler) removeWaiter(result chan deckResult) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, waiter := range s.waiters {
+		if waiter == result {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// acquire takes a generation slot, queueing behind callers of the same or
+// higher priority when none is free
+func (s *GenerationScheduler) acquire(ctx context.Context, priority GenerationPriority) error {
+	s.mu.Lock()
+	if s.running < s.slots {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	s.queued[priority] = append(s.queued[priority], ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		queue := s.queued[priority]
+		for i, ch := range queue {
+			if ch == ready {
+				s.queued[priority] = append(queue[:i], queue[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was granted as we gave up; pass it on
+		s.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+func (s *GenerationScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+// releaseLocked hands the slot to the next queued caller, live requests
+// first. Assumes s.mu is already locked.
+func (s *GenerationScheduler) releaseLocked() {
+	for _, priority := range []GenerationPriority{PriorityLive, PriorityPreload} {
+		if queue := s.queued[priority]; len(queue) > 0 {
+			s.queued[priority] = queue[1:]
+			close(queue[0]) // The slot passes straight over; running is unchanged
+			return
+		}
+	}
+	s.running--
+}
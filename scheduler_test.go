@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedGeneration is a generate func whose decks only finish when released
+type gatedGeneration struct {
+	mu      sync.Mutex
+	started []string
+	release chan string
+}
+
+func newGatedGeneration() *gatedGeneration {
+	return &gatedGeneration{release: make(chan string, 16)}
+}
+
+func (g *gatedGeneration) generate(ctx context.Context, onProgress func(GameContent)) (*GameContent, error) {
+	g.mu.Lock()
+	g.started = append(g.started, "")
+	g.mu.Unlock()
+
+	select {
+	case name := <-g.release:
+		if name == "" {
+			return nil, errors.New("generation failed")
+		}
+		return &GameContent{BusinessName: name}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *gatedGeneration) startedCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.started)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerHandsPreloadedDeckToWaiter(t *testing.T) {
+	gen := newGatedGeneration()
+	cache := NewContentCache(5)
+	scheduler := NewGenerationScheduler(2, cache, gen.generate)
+
+	go scheduler.Produce(context.Background(), PriorityPreload)
+	waitFor(t, "preload to start", func() bool { return gen.startedCount() == 1 })
+
+	result := make(chan *DeckBuild, 1)
+	go func() {
+		deck, err := scheduler.Await(context.Background())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		result <- deck
+	}()
+	waitFor(t, "live generation to start", func() bool { return gen.startedCount() == 2 })
+
+	// Whichever deck finishes first goes to the presenter, the other to the cache
+	gen.release <- "First"
+	if state, _ := (<-result).State(); state.Content.BusinessName != "First" {
+		t.Errorf("expected the first finished deck, got %+v", state)
+	}
+	gen.release <- "Second"
+	waitFor(t, "spare deck to be cached", func() bool { return cache.Size() == 1 })
+
+	// The rate the preloader plans by must see decks that skipped the cache
+	if consumed := cache.Stats().Consumed; consumed != 1 || cache.PopsSince(time.Now().Add(-time.Minute)) != 1 {
+		t.Errorf("expected the handed-over deck to count as consumed, got %d", consumed)
+	}
+}
+
+func TestSchedulerCoalescesWaiters(t *testing.T) {
+	gen := newGatedGeneration()
+	scheduler := NewGenerationScheduler(4, NewContentCache(5), gen.generate)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := scheduler.Await(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	waitFor(t, "both waiters", func() bool { return scheduler.Stats().Waiting == 2 })
+
+	if started := gen.startedCount(); started != 2 {
+		t.Errorf("expected one generation per waiter, got %d", started)
+	}
+
+	gen.release <- "One"
+	gen.release <- "Two"
+	wg.Wait()
+	if started := gen.startedCount(); started != 2 {
+		t.Errorf("expected no extra generations, got %d", started)
+	}
+}
+
+func TestSchedulerPrioritisesLiveRequests(t *testing.T) {
+	gen := newGatedGeneration()
+	cache := NewContentCache(5)
+	scheduler := NewGenerationScheduler(1, cache, gen.generate)
+
+	go scheduler.Produce(context.Background(), PriorityPreload)
+	waitFor(t, "first preload", func() bool { return gen.startedCount() == 1 })
+	go scheduler.Produce(context.Background(), PriorityPreload)
+	waitFor(t, "second preload to queue", func() bool { return scheduler.Stats().QueuedPreload == 1 })
+
+	go scheduler.Await(context.Background())
+	waitFor(t, "live request to queue", func() bool { return scheduler.Stats().QueuedLive == 1 })
+
+	// Freeing the slot must start the live generation, not the queued preload
+	gen.release <- "Preloaded"
+	waitFor(t, "slot handover", func() bool { return gen.startedCount() == 2 })
+	if stats := scheduler.Stats(); stats.QueuedLive != 0 || stats.QueuedPreload != 1 {
+		t.Errorf("expected the live request to take the slot, got %+v", stats)
+	}
+	gen.release <- "Live"
+	gen.release <- "Later"
+}
+
+func TestSchedulerHandsOverLiveDeckOnceItsPitchIsReady(t *testing.T) {
+	images := make(chan struct{})
+	generate := func(ctx context.Context, onProgress func(GameContent)) (*GameContent, error) {
+		content := GameContent{BusinessName: "Pitch First", Slogan: "Images later"}
+		onProgress(content)
+		<-images
+		content.Image1, content.Image2 = "image1", "image2"
+		onProgress(content)
+		return &content, nil
+	}
+	scheduler := NewGenerationScheduler(1, NewContentCache(5), generate)
+
+	deck, err := scheduler.Await(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state, _ := deck.State()
+	if !state.HasPitch() || state.Done || state.Content.Image1 != "" {
+		t.Fatalf("expected the pitch before the images, got %+v", state)
+	}
+
+	close(images)
+	state, err = deck.Wait(context.Background())
+	if err != nil || state.Err != nil || state.Content.Image2 != "image2" {
+		t.Errorf("expected the deck to finish with its images, got %+v (%v)", state, err)
+	}
+}
+
+func TestSchedulerAwaitFailsWhenLiveGenerationFails(t *testing.T) {
+	gen := newGatedGeneration()
+	scheduler := NewGenerationScheduler(1, NewContentCache(5), gen.generate)
+
+	gen.release <- ""
+	if _, err := scheduler.Await(context.Background()); err == nil {
+		t.Fatal("expected the generation error")
+	}
+	if waiting := scheduler.Stats().Waiting; waiting != 0 {
+		t.Errorf("expected the failed waiter to be removed, got %d waiting", waiting)
+	}
+}
+
+func TestSchedulerWithholdsDecksFromAPreviousTheme(t *testing.T) {
+	gen := newGatedGeneration()
+	cache := NewContentCache(5)
+	// Like the app, a deck records the theme it was started under
+	generate := func(ctx context.Context, onProgress func(GameContent)) (*GameContent, error) {
+		theme := cache.Theme()
+		content, err := gen.generate(ctx, onProgress)
+		if content != nil {
+			content.Theme = theme
+		}
+		return content, err
+	}
+	scheduler := NewGenerationScheduler(2, cache, generate)
+
+	result := make(chan *DeckBuild, 1)
+	go func() {
+		deck, err := scheduler.Await(context.Background())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		result <- deck
+	}()
+	waitFor(t, "live generation to start", func() bool { return gen.startedCount() == 1 })
+
+	cache.SetTheme("Space week")
+	gen.release <- "Off Theme"
+	waitFor(t, "a replacement generation", func() bool { return gen.startedCount() == 2 })
+	select {
+	case deck := <-result:
+		state, _ := deck.State()
+		t.Fatalf("expected the waiter to keep waiting, got %+v", state.Content)
+	default:
+	}
+
+	gen.release <- "On Theme"
+	if state, _ := (<-result).State(); state.Content.BusinessName != "On Theme" || state.Content.Theme != "Space week" {
+		t.Errorf("expected an on-theme deck, got %+v", state.Content)
+	}
+	if stats := cache.Stats(); stats.Rethemed != 1 || cache.Size() != 0 {
+		t.Errorf("expected the off-theme deck to be dropped, got %+v with %d cached", stats, cache.Size())
+	}
+}
+
+func TestSchedulerHoldsDecksForReview(t *testing.T) {
+	gen := newGatedGeneration()
+	cache := NewContentCache(5)
+	scheduler := NewGenerationScheduler(2, cache, gen.generate)
+
+	result := make(chan *DeckBuild, 1)
+	go func() {
+		deck, err := scheduler.Await(context.Background())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		result <- deck
+	}()
+	waitFor(t, "live generation to start", func() bool { return gen.startedCount() == 1 })
+
+	// The host turns review mode on while the presenter is already waiting
+	cache.SetReviewRequired(true)
+	scheduler.AwaitReview()
+	gen.release <- "Unreviewed"
+	waitFor(t, "deck to be held for review", func() bool { return cache.Size() == 1 })
+	select {
+	case deck := <-result:
+		state, _ := deck.State()
+		t.Fatalf("expected the presenter to wait for approval, got %+v", state.Content)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cache.Approve(cache.Items()[0].ID)
+	select {
+	case deck := <-result:
+		if state, _ := deck.State(); state.Content.BusinessName != "Unreviewed" {
+			t.Errorf("expected the approved deck, got %+v", state.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the approved deck to reach the presenter")
+	}
+}
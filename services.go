@@ -2,14 +2,18 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"google.golang.org/genai"
 )
@@ -63,9 +67,33 @@ func retryWithBackoff(ctx context.Context, config RetryConfig, operation func()
 }
 
 type Generator interface {
-	GenerateBusinessIdea(ctx context.Context) (string, string, error)
-	GenerateImagePrompt(ctx context.Context) (string, error)
-	GenerateImage(ctx context.Context, prompt string) (string, error)
+	GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error)
+	// GenerateImagePrompt writes a prompt for a random absurd scene, or for a
+	// scene that supports the deck's pitch when a brief is given
+	GenerateImagePrompt(ctx context.Context, brief *DeckBrief, opts GenerationOptions) (string, error)
+	GenerateImage(ctx context.Context, prompt string) (*GeneratedImage, error)
+}
+
+// SlideRole says what an image slide contributes to the pitch
+type SlideRole string
+
+const (
+	SlideRoleProblem SlideRole = "problem" // The absurd problem the business solves
+	SlideRoleProduct SlideRole = "product" // The business's product in action
+)
+
+// DeckBrief ties an image prompt to the deck's business idea
+type DeckBrief struct {
+	BusinessName string
+	Slogan       string
+	Role         SlideRole
+}
+
+// GenerationOptions are the event-wide settings every generated idea and
+// scene has to follow
+type GenerationOptions struct {
+	Theme    Theme    // Event theme, if any
+	Glossary Glossary // Company terms to reference and topics to avoid
 }
 
 type GiphyClient interface {
@@ -75,9 +103,10 @@ type GiphyClient interface {
 type AiGenerator struct {
 	googleAPIKey string
 	client       *genai.Client
+	prompts      *PromptStore // Word lists and instructions; nil uses the built-in pack
 }
 
-func NewAiGenerator(apiKey string) (*AiGenerator, error) {
+func NewAiGenerator(apiKey string, prompts *PromptStore) (*AiGenerator, error) {
 	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
 		APIKey: apiKey,
 	})
@@ -87,98 +116,233 @@ func NewAiGenerator(apiKey string) (*AiGenerator, error) {
 	return &AiGenerator{
 		googleAPIKey: apiKey,
 		client:       client,
+		prompts:      prompts,
 	}, nil
 }
 
-func (g *AiGenerator) GenerateBusinessIdea(ctx context.Context) (string, string, error) {
-	businessTypes := []string{"a mobile app", "a subscription box", "a gourmet food truck", "a line of smart home devices", "a bespoke tailoring service", "a virtual reality arcade", "an artisanal coffee shop", "a pet psychic agency", "a zero-gravity yoga studio"}
-	targetAudiences := []string{"time-traveling tourists", "sentient houseplants", "retired superheroes", "aliens on vacation", "ghosts with unfinished business", "zombies who are into personal growth", "dolphins who want to be web developers", "cats who are learning to code", "very-online vampires"}
-	absurdProblems := []string{"socks that are always lonely", "pigeons that are too loud", "a toaster with an attitude problem", "the existential dread of a Roomba", "lost TV remotes", "dreams that are too boring", "awkward silences in elevators", "when your pet starts talking about philosophy", "running out of things to watch on streaming services"}
-
-	request := BusinessIdeaRequest{
-		BusinessType:   getRandomElement(businessTypes),
-		TargetAudience: getRandomElement(targetAudiences),
-		AbsurdProblem:  getRandomElement(absurdProblems),
-		Instructions:   "Generate a fake, humorous business name and a slogan for it based on the fields above. Return it as 'Name: <name> Slogan: <slogan>'",
-	}
-
+func (g *AiGenerator) GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error) {
+	request := g.businessIdeaRequest(opts)
 	jsonRequest, err := json.Marshal(request)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to marshal business idea request: %w", err)
 	}
 
 	config := &genai.GenerateContentConfig{
-		Temperature: genai.Ptr[float32](0.9),
+		Temperature:      genai.Ptr[float32](0.9),
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   businessIdeaSchema,
+		SafetySettings:   safetySettings,
 	}
 
-	finalPrompt := fmt.Sprintf("Based on the following JSON, fulfill the instructions:\n\n%s", string(jsonRequest))
-	prompt := genai.Text(finalPrompt)
+	basePrompt := fmt.Sprintf("Based on the following JSON, fulfill the instructions:\n\n%s", string(jsonRequest))
+	finalPrompt := basePrompt
 
-	var resp *genai.GenerateContentResponse
-	err = retryWithBackoff(ctx, DefaultRetryConfig, func() error {
-		var apiErr error
-		resp, apiErr = g.client.Models.GenerateContent(ctx, "gemini-1.5-pro-latest", prompt, config)
-		return apiErr
-	})
+	// Re-ask a bounded number of times if the model's answer does not validate
+	var lastErr error
+	for attempt := 1; attempt <= maxBusinessIdeaAttempts; attempt++ {
+		var resp *genai.GenerateContentResponse
+		err = retryWithBackoff(ctx, DefaultRetryConfig, func() error {
+			var apiErr error
+			resp, apiErr = g.client.Models.GenerateContent(ctx, "gemini-1.5-pro-latest", genai.Text(finalPrompt), config)
+			return apiErr
+		})
 
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate business idea after retries: %w", err)
-	}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate business idea after retries: %w", err)
+		}
+		if err := checkResponseSafety(resp); err != nil {
+			return "", "", err
+		}
 
-	var textContent string
-	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
-		for _, part := range resp.Candidates[0].Content.Parts {
-			if part.Text != "" {
-				textContent = string(part.Text)
-				break
-			}
+		idea, err := parseBusinessIdea(resp.Text())
+		if err == nil {
+			return idea.Name, idea.Slogan, nil
 		}
+
+		lastErr = err
+		log.Printf("Business idea attempt %d/%d rejected: %v", attempt, maxBusinessIdeaAttempts, err)
+		finalPrompt = fmt.Sprintf("%s\n\nA previous answer was rejected because %v. Try again and follow the instructions exactly.", basePrompt, err)
 	}
 
-	if textContent == "" {
-		return "", "", fmt.Errorf("failed to extract text from Gemini response")
+	return "", "", fmt.Errorf("no valid business idea after %d attempts: %w", maxBusinessIdeaAttempts, lastErr)
+}
+
+const (
+	maxBusinessNameLength   = 60
+	maxSloganLength         = 140
+	maxBusinessIdeaAttempts = 3
+)
+
+// BusinessIdea is the structured response Gemini returns for a business idea
+type BusinessIdea struct {
+	Name   string `json:"name"`
+	Slogan string `json:"slogan"`
+}
+
+var businessIdeaSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"name": {
+			Type:        genai.TypeString,
+			Description: "The fake business name, in plain text",
+			MinLength:   genai.Ptr[int64](1),
+			MaxLength:   genai.Ptr[int64](maxBusinessNameLength),
+		},
+		"slogan": {
+			Type:        genai.TypeString,
+			Description: "A one-sentence slogan for the business, in plain text",
+			MinLength:   genai.Ptr[int64](1),
+			MaxLength:   genai.Ptr[int64](maxSloganLength),
+		},
+	},
+	Required:         []string{"name", "slogan"},
+	PropertyOrdering: []string{"name", "slogan"},
+}
+
+// parseBusinessIdea decodes and validates a JSON business idea from the model
+func parseBusinessIdea(text string) (BusinessIdea, error) {
+	var idea BusinessIdea
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &idea); err != nil {
+		return BusinessIdea{}, fmt.Errorf("response is not valid JSON: %w", err)
 	}
 
-	parts := strings.Split(textContent, "Slogan:")
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("unexpected response format from Gemini: %s", textContent)
+	idea.Name = strings.TrimSpace(idea.Name)
+	idea.Slogan = strings.TrimSpace(idea.Slogan)
+	if err := idea.Validate(); err != nil {
+		return BusinessIdea{}, err
 	}
-	namePart := strings.TrimSpace(strings.TrimPrefix(parts[0], "Name:"))
-	sloganPart := strings.TrimSpace(parts[1])
+	return idea, nil
+}
+
+// Validate checks that the idea is short, plain text that fits on a slide
+func (idea BusinessIdea) Validate() error {
+	fields := []struct {
+		label     string
+		value     string
+		maxLength int
+	}{
+		{"name", idea.Name, maxBusinessNameLength},
+		{"slogan", idea.Slogan, maxSloganLength},
+	}
+
+	for _, field := range fields {
+		if field.value == "" {
+			return fmt.Errorf("the %s is empty", field.label)
+		}
+		if n := utf8.RuneCountInString(field.value); n > field.maxLength {
+			return fmt.Errorf("the %s is %d characters, over the limit of %d", field.label, n, field.maxLength)
+		}
+		if strings.ContainsAny(field.value, "\n\r*#`[]<>") {
+			return fmt.Errorf("the %s contains markdown or line breaks", field.label)
+		}
+		lower := strings.ToLower(field.value)
+		if strings.Contains(lower, "name:") || strings.Contains(lower, "slogan:") {
+			return fmt.Errorf("the %s contains a field label", field.label)
+		}
+	}
+
+	if strings.EqualFold(idea.Name, idea.Slogan) {
+		return fmt.Errorf("the slogan repeats the name")
+	}
+	return nil
+}
 
-	return namePart, sloganPart, nil
+// businessIdeaRequest picks the ingredients of a business idea, steered by
+// the event theme and the company glossary
+func (g *AiGenerator) businessIdeaRequest(opts GenerationOptions) BusinessIdeaRequest {
+	pack := g.prompts.Pack()
+	request := BusinessIdeaRequest{
+		BusinessType:   getRandomElement(pack.BusinessTypes),
+		TargetAudience: getRandomElement(pack.TargetAudiences),
+		AbsurdProblem:  getRandomElement(pack.AbsurdProblems),
+		Instructions:   pack.businessIdeaInstructions(),
+	}
+	if theme := opts.Theme; !theme.IsZero() {
+		request.Theme = theme.Text
+		request.StyleKeywords = theme.Keywords
+		request.Instructions += " The business must fit the event theme and the style keywords."
+	}
+	glossary := opts.Glossary
+	if term, ok := glossary.sample(); ok {
+		request.InsideJoke = &term
+		request.Instructions += " Work the inside_joke into the name or slogan so the audience gets the reference."
+	}
+	if len(glossary.DoNotMention) > 0 {
+		request.AvoidMentioning = glossary.DoNotMention
+		request.Instructions += " Never mention anything in avoid_mentioning."
+	}
+	return request
 }
 
 type BusinessIdeaRequest struct {
-	BusinessType   string `json:"business_type"`
-	TargetAudience string `json:"target_audience"`
-	AbsurdProblem  string `json:"absurd_problem"`
-	Instructions   string `json:"instructions"`
+	BusinessType    string        `json:"business_type"`
+	TargetAudience  string        `json:"target_audience"`
+	AbsurdProblem   string        `json:"absurd_problem"`
+	Theme           string        `json:"theme,omitempty"`
+	StyleKeywords   []string      `json:"style_keywords,omitempty"`
+	InsideJoke      *GlossaryTerm `json:"inside_joke,omitempty"`
+	AvoidMentioning []string      `json:"avoid_mentioning,omitempty"`
+	Instructions    string        `json:"instructions"`
 }
 
 type ImagePromptRequest struct {
-	CharacterAgeRange string `json:"character_age_range"`
-	Setting           string `json:"setting"`
-	AbsurdTwist       string `json:"absurd_twist"`
-	VisualStyle       string `json:"visual_style"`
-	FinalPrompt       string `json:"final_prompt"`
+	BusinessName      string        `json:"business_name,omitempty"`
+	Slogan            string        `json:"slogan,omitempty"`
+	SceneGoal         string        `json:"scene_goal,omitempty"`
+	CharacterAgeRange string        `json:"character_age_range"`
+	Setting           string        `json:"setting"`
+	AbsurdTwist       string        `json:"absurd_twist"`
+	VisualStyle       string        `json:"visual_style"`
+	Theme             string        `json:"theme,omitempty"`
+	StyleKeywords     []string      `json:"style_keywords,omitempty"`
+	InsideJoke        *GlossaryTerm `json:"inside_joke,omitempty"`
+	AvoidMentioning   []string      `json:"avoid_mentioning,omitempty"`
+	FinalPrompt       string        `json:"final_prompt"`
 }
 
 func getRandomElement(slice []string) string {
 	return slice[rand.Intn(len(slice))]
 }
 
-func (g *AiGenerator) GenerateImagePrompt(ctx context.Context) (string, error) {
-	characterAges := []string{"child", "teenager", "adult", "middle-aged", "elderly"}
-	settings := []string{"unexpected public place", "outer space", "underwater", "historic era", "corporate office", "dreamlike zone"}
-	absurdTwists := []string{"prop or situation that contradicts logic or expectations", "a mundane task performed in an extreme environment", "animals behaving like humans in a specific, detailed way", "a historical figure using modern technology", "an inanimate object coming to life with a strong personality"}
-
+func (g *AiGenerator) GenerateImagePrompt(ctx context.Context, brief *DeckBrief, opts GenerationOptions) (string, error) {
+	pack := g.prompts.Pack()
 	request := ImagePromptRequest{
-		CharacterAgeRange: getRandomElement(characterAges),
-		Setting:           getRandomElement(settings),
-		AbsurdTwist:       getRandomElement(absurdTwists),
-		VisualStyle:       "photorealistic",
-		FinalPrompt:       "[Write a single, richly detailed, photorealistic image prompt for a SFW AI image generator. It should use these fields to describe a vivid, absurd and comedic scene. The description must be specific, visual, and funny — like something from a dream or a comedy sketch. Avoid clichés, generic phrasing and jokes involving suicide.]",
+		CharacterAgeRange: getRandomElement(pack.CharacterAges),
+		Setting:           getRandomElement(pack.Settings),
+		AbsurdTwist:       getRandomElement(pack.AbsurdTwists),
+		VisualStyle:       pack.VisualStyle,
+		FinalPrompt:       pack.ImagePromptInstructions,
+	}
+
+	// In a coherent deck the scene has to support the pitch on the previous slide
+	if brief != nil {
+		request.BusinessName = brief.BusinessName
+		request.Slogan = brief.Slogan
+		switch brief.Role {
+		case SlideRoleProblem:
+			request.SceneGoal = pack.ProblemSceneGoal
+		case SlideRoleProduct:
+			request.SceneGoal = pack.ProductSceneGoal
+		}
+		request.FinalPrompt = pack.CoherentImagePromptInstructions
+	}
+
+	// Every scene fits the event theme, if there is one
+	if theme := opts.Theme; !theme.IsZero() {
+		request.Theme = theme.Text
+		request.StyleKeywords = theme.Keywords
+		request.FinalPrompt += " [The scene must fit the event theme and the style keywords.]"
+	}
+
+	// Sometimes the scene references a company in-joke
+	glossary := opts.Glossary
+	if term, ok := glossary.sample(); ok {
+		request.InsideJoke = &term
+		request.FinalPrompt += " [Work the inside_joke into the scene.]"
+	}
+	if len(glossary.DoNotMention) > 0 {
+		request.AvoidMentioning = glossary.DoNotMention
+		request.FinalPrompt += " [Never show or mention anything in avoid_mentioning.]"
 	}
 
 	jsonRequest, err := json.Marshal(request)
@@ -189,6 +353,7 @@ func (g *AiGenerator) GenerateImagePrompt(ctx context.Context) (string, error) {
 	config := &genai.GenerateContentConfig{
 		Temperature:     genai.Ptr[float32](0.9),
 		MaxOutputTokens: 300,
+		SafetySettings:  safetySettings,
 	}
 
 	finalPrompt := fmt.Sprintf("Based on the following JSON, generate the 'final_prompt':\n\n%s", string(jsonRequest))
@@ -204,13 +369,19 @@ func (g *AiGenerator) GenerateImagePrompt(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to generate image prompt after retries: %w", err)
 	}
+	if err := checkResponseSafety(resp); err != nil {
+		return "", err
+	}
 
 	return resp.Text(), nil
 }
 
-func (g *AiGenerator) GenerateImage(ctx context.Context, prompt string) (string, error) {
+func (g *AiGenerator) GenerateImage(ctx context.Context, prompt string) (*GeneratedImage, error) {
 	config := &genai.GenerateImagesConfig{
-		NumberOfImages: 1,
+		NumberOfImages:    1,
+		SafetyFilterLevel: genai.SafetyFilterLevelBlockLowAndAbove,
+		PersonGeneration:  genai.PersonGenerationAllowAdult,
+		IncludeRAIReason:  true,
 	}
 
 	var response *genai.GenerateImagesResponse
@@ -226,14 +397,29 @@ func (g *AiGenerator) GenerateImage(ctx context.Context, prompt string) (string,
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("failed to generate image after retries: %w", err)
+		return nil, fmt.Errorf("failed to generate image after retries: %w", err)
 	}
 
+	var filtered []string
 	for _, image := range response.GeneratedImages {
-		return fmt.Sprintf("data:image/png;base64,%s", base64.StdEncoding.EncodeToString(image.Image.ImageBytes)), nil
+		if image.RAIFilteredReason != "" {
+			filtered = append(filtered, image.RAIFilteredReason)
+			continue
+		}
+		if image.Image == nil || len(image.Image.ImageBytes) == 0 {
+			continue
+		}
+		mimeType := image.Image.MIMEType
+		if mimeType == "" {
+			mimeType = "image/png"
+		}
+		return &GeneratedImage{Data: image.Image.ImageBytes, MIMEType: mimeType}, nil
 	}
 
-	return "", fmt.Errorf("no image data in response from prompt: %s", prompt)
+	if len(filtered) > 0 {
+		return nil, fmt.Errorf("%w: image filtered (%s)", ErrContentRejected, strings.Join(filtered, "; "))
+	}
+	return nil, fmt.Errorf("no image data in response from prompt: %s", prompt)
 }
 
 func (app *App) GetClappingGiphy(ctx context.Context) (string, error) {
@@ -326,51 +512,282 @@ func (app *App) getRandomGifFromCache() string {
 	return gif
 }
 
-// generateGameContent creates a complete GameContent with all required assets
-func (app *App) generateGameContent(ctx context.Context) (*GameContent, error) {
-	// Generate business idea
-	businessName, slogan, err := app.generator.GenerateBusinessIdea(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate business idea: %w", err)
+// stageGroup runs generation stages concurrently. The first stage to fail
+// cancels the others, and Wait reports every failure that was not just a
+// consequence of that cancellation.
+type stageGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	errs   []error
+}
+
+func newStageGroup(ctx context.Context) *stageGroup {
+	ctx, cancel := context.WithCancel(ctx)
+	return &stageGroup{ctx: ctx, cancel: cancel}
+}
+
+// Go runs a stage in its own goroutine. Stages may start further stages.
+func (g *stageGroup) Go(stage func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := stage(g.ctx); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			g.cancel()
+		}
+	}()
+}
+
+// Wait blocks until every stage has finished and returns the aggregated error
+func (g *stageGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	var causes []error
+	for _, err := range g.errs {
+		if !errors.Is(err, context.Canceled) {
+			causes = append(causes, err)
+		}
+	}
+	if len(causes) == 0 {
+		// Only cancellations, so the caller's context was cancelled
+		return errors.Join(g.errs...)
+	}
+	return errors.Join(causes...)
+}
+
+// generationOptions returns the settings a new generation runs under
+func (app *App) generationOptions() GenerationOptions {
+	return GenerationOptions{Theme: app.currentTheme(), Glossary: app.currentGlossary()}
+}
+
+// generateGameContent creates a complete GameContent with all required assets.
+// Independent stages run concurrently, so the deck takes about as long as its
+// slowest chain of dependent calls. If onProgress is set it is called with the
+// parts generated so far each time one is added.
+func (app *App) generateGameContent(ctx context.Context, onProgress func(GameContent)) (*GameContent, error) {
+	// The whole deck is generated under the theme in force when it started
+	opts := app.generationOptions()
+
+	var (
+		partialMu sync.Mutex
+		partial   = GameContent{Theme: opts.Theme.String()}
+	)
+	report := func(fill func(content *GameContent)) {
+		partialMu.Lock()
+		defer partialMu.Unlock()
+		fill(&partial)
+		if onProgress != nil {
+			onProgress(partial)
+		}
 	}
 
+	group := newStageGroup(ctx)
+
 	// Generate clapping GIF
-	clappingGif, err := app.GetClappingGiphy(ctx)
-	if err != nil {
-		log.Printf("Failed to get clapping gif: %v", err)
-		clappingGif = "https://media.giphy.com/media/3o7abB06u9bNzA8lu8/giphy.gif"
+	group.Go(func(ctx context.Context) error {
+		clappingGif, err := app.GetClappingGiphy(ctx)
+		if err != nil {
+			log.Printf("Failed to get clapping gif: %v", err)
+			clappingGif = "https://media.giphy.com/media/3o7abB06u9bNzA8lu8/giphy.gif"
+		}
+		report(func(content *GameContent) { content.ClappingGif = clappingGif })
+		return nil
+	})
+
+	// generateImage runs one prompt-then-image chain into image slide n
+	generateImage := func(n int, brief *DeckBrief) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			imagePrompt, err := app.generator.GenerateImagePrompt(ctx, brief, opts)
+			if err != nil {
+				return fmt.Errorf("failed to generate image prompt %d: %w", n, err)
+			}
+
+			generated, err := app.generator.GenerateImage(ctx, imagePrompt)
+			if err != nil {
+				return fmt.Errorf("failed to generate image %d: %w", n, err)
+			}
+			image, err := app.storeImage(generated)
+			if err != nil {
+				return fmt.Errorf("failed to generate image %d: %w", n, err)
+			}
+			report(func(content *GameContent) {
+				if n == 1 {
+					content.Image1 = image
+				} else {
+					content.Image2 = image
+				}
+			})
+			return nil
+		}
 	}
 
-	// Generate first image
-	imagePrompt1, err := app.generator.GenerateImagePrompt(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate image prompt 1: %w", err)
+	// Generate business idea
+	group.Go(func(ctx context.Context) error {
+		businessName, slogan, err := app.generator.GenerateBusinessIdea(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to generate business idea: %w", err)
+		}
+		report(func(content *GameContent) {
+			content.BusinessName = businessName
+			content.Slogan = slogan
+		})
+
+		// In a coherent deck the images illustrate the problem and the product,
+		// so they have to wait for the business idea
+		if app.coherentDeck {
+			group.Go(generateImage(1, &DeckBrief{BusinessName: businessName, Slogan: slogan, Role: SlideRoleProblem}))
+			group.Go(generateImage(2, &DeckBrief{BusinessName: businessName, Slogan: slogan, Role: SlideRoleProduct}))
+		}
+		return nil
+	})
+
+	// Otherwise they are unrelated random scenes and can start straight away
+	if !app.coherentDeck {
+		group.Go(generateImage(1, nil))
+		group.Go(generateImage(2, nil))
 	}
 
-	image1, err := app.generator.GenerateImage(ctx, imagePrompt1)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate image 1: %w", err)
+	if err := group.Wait(); err != nil {
+		return nil, err
 	}
 
-	// Generate second image
-	imagePrompt2, err := app.generator.GenerateImagePrompt(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate image prompt 2: %w", err)
+	content := partial
+	content.CreatedAt = time.Now()
+	return &content, nil
+}
+
+// Parts of a deck that can be regenerated on their own. They match the game
+// stream's event types.
+const (
+	SlidePitch       = "pitch"
+	SlideImage1      = "image1"
+	SlideImage2      = "image2"
+	SlideClappingGif = "clappingGif"
+)
+
+// rerollTimeout bounds how long regenerating one slide may take, including
+// waiting for a generation slot
+const rerollTimeout = 2 * time.Minute
+
+// rerollSlide regenerates one part of a game session's deck and pushes the
+// replacement to the game page. The caller must hold a generation slot.
+func (app *App) rerollSlide(ctx context.Context, sessionID, part string) error {
+	session, ok := app.sessions.Get(sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	opts := app.generationOptions()
+
+	var fill func(content *GameContent)
+	switch part {
+	case SlidePitch:
+		businessName, slogan, err := app.generator.GenerateBusinessIdea(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to generate business idea: %w", err)
+		}
+		fill = func(content *GameContent) {
+			content.BusinessName = businessName
+			content.Slogan = slogan
+		}
+	case SlideImage1, SlideImage2:
+		var brief *DeckBrief
+		if app.coherentDeck {
+			brief = &DeckBrief{BusinessName: session.Content.BusinessName, Slogan: session.Content.Slogan, Role: SlideRoleProblem}
+			if part == SlideImage2 {
+				brief.Role = SlideRoleProduct
+			}
+		}
+		imagePrompt, err := app.generator.GenerateImagePrompt(ctx, brief, opts)
+		if err != nil {
+			return fmt.Errorf("failed to generate image prompt: %w", err)
+		}
+		generated, err := app.generator.GenerateImage(ctx, imagePrompt)
+		if err != nil {
+			return fmt.Errorf("failed to generate image: %w", err)
+		}
+		image, err := app.storeImage(generated)
+		if err != nil {
+			return fmt.Errorf("failed to generate image: %w", err)
+		}
+		fill = func(content *GameContent) {
+			if part == SlideImage1 {
+				content.Image1 = image
+			} else {
+				content.Image2 = image
+			}
+		}
+	case SlideClappingGif:
+		clappingGif, err := app.GetClappingGiphy(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get clapping gif: %w", err)
+		}
+		fill = func(content *GameContent) { content.ClappingGif = clappingGif }
+	default:
+		return fmt.Errorf("unknown slide %q", part)
 	}
 
-	image2, err := app.generator.GenerateImage(ctx, imagePrompt2)
+	session, err := app.sessions.ReplaceContent(sessionID, fill)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate image 2: %w", err)
+		return err
 	}
+	event := deckPartEvent(part, session.Content)
+	event.SessionID = session.ID
+	app.publish("slide", event)
+	return nil
+}
 
-	return &GameContent{
-		BusinessName: businessName,
-		Slogan:       slogan,
-		Image1:       image1,
-		Image2:       image2,
-		ClappingGif:  clappingGif,
-		CreatedAt:    time.Now(),
-	}, nil
+// errSlidesMissing marks a deck whose failed slides could not be repaired
+var errSlidesMissing = errors.New("slides could not be regenerated")
+
+// repairDeck regenerates the slides a session's deck failed to generate after
+// its talk had started. Each goes to the game page as it lands; if any can't
+// be made, the page is told to stop waiting for it.
+func (app *App) repairDeck(sessionID string) {
+	session, ok := app.sessions.Get(sessionID)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), rerollTimeout)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Bool
+	)
+	for _, part := range []string{SlideImage1, SlideImage2, SlideClappingGif} {
+		if hasDeckPart(session.Content, part) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := app.scheduler.RunLive(ctx, func(ctx context.Context) error {
+				return app.rerollSlide(ctx, sessionID, part)
+			})
+			if err != nil {
+				log.Printf("Failed to repair %s for session %s: %v", part, sessionID, err)
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var err error
+	if failed.Load() {
+		err = errSlidesMissing
+		app.publish("slide", deckStreamEvent{Type: "failed", SessionID: sessionID})
+	} else {
+		log.Printf("Repaired the deck for session %s", sessionID)
+	}
+	// A reloaded page shows the repaired deck, or stops waiting for the slides
+	if session, ok := app.sessions.Get(sessionID); ok {
+		session.Deck().finish(&session.Content, err)
+	}
 }
 
 // StartContentPreloader starts the background content preloader
@@ -385,7 +802,7 @@ func (app *App) StartContentPreloader(ctx context.Context) {
 	app.preloadRunning = true
 	app.preloadStop = make(chan struct{})
 
-	go app.runContentPreloader(ctx)
+	go app.runContentPreloader(ctx, app.preloadStop)
 }
 
 // StopContentPreloader stops the background content preloader
@@ -409,65 +826,192 @@ func (app *App) isPreloadRunning() bool {
 }
 
 // runContentPreloader is the main preloader loop
-func (app *App) runContentPreloader(ctx context.Context) {
+func (app *App) runContentPreloader(ctx context.Context, stop <-chan struct{}) {
 	log.Println("Starting content preloader...")
 
-	// Initial load - fill cache to 80% capacity
-	initialTarget := int(float64(app.contentCache.maxSize) * 0.8)
-	for i := 0; i < initialTarget; i++ {
+	// Stopping the preloader also abandons any generation in flight
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
 		select {
-		case <-app.preloadStop:
-			log.Println("Content preloader stopped during initial load")
-			return
+		case <-stop:
+			cancel()
 		case <-ctx.Done():
-			log.Println("Content preloader stopped due to context cancellation")
-			return
-		default:
 		}
+	}()
 
-		content, err := app.generateGameContent(ctx)
-		if err != nil {
-			log.Printf("Failed to generate content during initial load: %v", err)
-			time.Sleep(5 * time.Second) // Wait before retrying
-			continue
-		}
-
-		app.contentCache.Push(*content)
-		log.Printf("Initial preload: generated content %d/%d", i+1, initialTarget)
+	// Initial load - fill cache to 80% capacity, counting anything restored from disk
+	targetSize := int(float64(app.contentCache.Capacity()) * 0.8)
+	app.refillCache(ctx, targetSize, 5*time.Second)
+	if ctx.Err() != nil {
+		log.Println("Content preloader stopped during initial load")
+		return
 	}
 
 	app.contentCache.SetLoaded()
 	log.Printf("Initial content preload completed. Cache size: %d", app.contentCache.Size())
 
-	// Maintenance loop - keep cache topped up
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
+	// Maintenance loop - keep cache topped up, as deep and as often as
+	// current consumption calls for
+	startedAt := time.Now()
 	for {
+		plan := app.planPreload(startedAt, time.Now())
+		app.setPreloadPlan(plan)
+		app.refillCache(ctx, plan.Target, 0)
+
 		select {
-		case <-app.preloadStop:
-			log.Println("Content preloader stopped")
-			return
 		case <-ctx.Done():
-			log.Println("Content preloader stopped due to context cancellation")
+			log.Println("Content preloader stopped")
 			return
-		case <-ticker.C:
-			// Check if cache needs refilling
-			cacheSize := app.contentCache.Size()
-			targetSize := int(float64(app.contentCache.maxSize) * 0.8)
+		case <-time.After(plan.Interval):
+		}
+	}
+}
 
-			if cacheSize < targetSize {
-				log.Printf("Cache low (%d/%d), generating new content...", cacheSize, targetSize)
+const (
+	consumptionWindow  = 15 * time.Minute // How far back the consumption rate is measured
+	preloadLeadTime    = 10 * time.Minute // How much consumption the cache should cover
+	minPreloadTarget   = 2                // Decks kept ready while the event is active
+	idlePreloadTarget  = 1                // Decks kept ready once nobody is playing
+	busyRefillInterval = 10 * time.Second
+	refillInterval     = 30 * time.Second
+	idleRefillInterval = 5 * time.Minute
+)
 
-				content, err := app.generateGameContent(ctx)
-				if err != nil {
-					log.Printf("Failed to generate content during maintenance: %v", err)
+// PreloadPlan is how deep the preloader keeps the cache and how often it checks
+type PreloadPlan struct {
+	Target   int
+	Interval time.Duration
+	Rate     float64 // Decks consumed per minute over the consumption window
+	Idle     bool
+}
+
+// planPreload measures recent consumption and plans the next refill. The
+// event counts as idle once nothing has been consumed for the idle timeout
+// since the later of the last Pop and the preloader starting.
+func (app *App) planPreload(startedAt, now time.Time) PreloadPlan {
+	lastActivity := startedAt
+	if lastPop := app.contentCache.LastPop(); lastPop.After(lastActivity) {
+		lastActivity = lastPop
+	}
+	pops := app.contentCache.PopsSince(now.Add(-consumptionWindow))
+	return computePreloadPlan(app.contentCache.Capacity(), pops, now.Sub(lastActivity), app.preloadIdleTimeout)
+}
+
+// computePreloadPlan sizes the cache to cover the lead time at the current
+// consumption rate, between a small floor and 80% of capacity
+func computePreloadPlan(maxSize, popsInWindow int, idleFor, idleTimeout time.Duration) PreloadPlan {
+	ceiling := max(int(float64(maxSize)*0.8), 1)
+
+	if idleTimeout > 0 && idleFor >= idleTimeout {
+		return PreloadPlan{
+			Target:   min(idlePreloadTarget, ceiling),
+			Interval: idleRefillInterval,
+			Idle:     true,
+		}
+	}
+
+	rate := float64(popsInWindow) / consumptionWindow.Minutes()
+	desired := int(math.Ceil(rate * preloadLeadTime.Minutes()))
+	plan := PreloadPlan{
+		Target:   min(max(desired, minPreloadTarget), ceiling),
+		Interval: refillInterval,
+		Rate:     rate,
+	}
+	if rate >= 0.5 { // A deck every two minutes or faster
+		plan.Interval = busyRefillInterval
+	}
+	return plan
+}
+
+// setPreloadPlan records the current plan for the admin page
+func (app *App) setPreloadPlan(plan PreloadPlan) {
+	app.preloadMu.Lock()
+	defer app.preloadMu.Unlock()
+
+	if plan != app.preloadPlan {
+		log.Printf("Preload plan: target %d, every %v (%.2f decks/min, idle=%t)", plan.Target, plan.Interval, plan.Rate, plan.Idle)
+	}
+	app.preloadPlan = plan
+}
+
+// currentPreloadPlan returns the plan the preloader is following
+func (app *App) currentPreloadPlan() PreloadPlan {
+	app.preloadMu.Lock()
+	defer app.preloadMu.Unlock()
+	return app.preloadPlan
+}
+
+// refillCache generates decks until the cache reaches targetSize, running more
+// workers the further below target it is. Each missing deck gets one attempt
+// per call; a worker waits failureDelay after a failure before moving on.
+func (app *App) refillCache(ctx context.Context, targetSize int, failureDelay time.Duration) {
+	cacheSize := app.contentCache.Size()
+	deficit := targetSize - cacheSize
+	if deficit <= 0 {
+		return
+	}
+
+	workers := refillWorkers(deficit, targetSize, app.preloadWorkers)
+	log.Printf("Cache low (%d/%d), generating %d decks with %d workers...", cacheSize, targetSize, deficit, workers)
+
+	jobs := make(chan int, deficit)
+	for i := 1; i <= deficit; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				// Under a byte budget the capacity is only known once decks
+				// have been measured, so the cache may fill up early
+				if app.contentCache.Size() >= min(targetSize, app.contentCache.Capacity()) {
+					return
+				}
+
+				// A presenter waiting on a deck gets it ahead of the cache
+				if err := app.scheduler.Produce(ctx, PriorityPreload); err != nil {
+					log.Printf("Failed to generate content %d/%d: %v", job, deficit, err)
+					select {
+					case <-ctx.Done():
+					case <-time.After(failureDelay): // Wait before moving on
+					}
 					continue
 				}
 
-				app.contentCache.Push(*content)
-				log.Printf("Generated new content. Cache size: %d", app.contentCache.Size())
+				log.Printf("Preload: generated content %d/%d. Cache size: %d", job, deficit, app.contentCache.Size())
 			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runCacheSweeper drops expired decks from the cache periodically, so the
+// preloader replaces them before anyone would be served a stale one
+func (app *App) runCacheSweeper(ttl time.Duration) {
+	ticker := time.NewTicker(min(max(ttl/10, 10*time.Second), 5*time.Minute))
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if expired := app.contentCache.Sweep(now); expired > 0 {
+			log.Printf("Dropped %d stale decks older than %v. Cache size: %d", expired, ttl, app.contentCache.Size())
 		}
 	}
 }
+
+// refillWorkers scales refill concurrency with how far below target the cache
+// is: a small dip trickles back with one worker, an empty cache gets them all
+func refillWorkers(deficit, targetSize, maxWorkers int) int {
+	if deficit <= 0 || targetSize <= 0 {
+		return 0
+	}
+	maxWorkers = max(maxWorkers, 1)
+	workers := (maxWorkers*deficit + targetSize - 1) / targetSize // Round up
+	return min(max(workers, 1), maxWorkers, deficit)
+}
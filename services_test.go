@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseBusinessIdea(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    BusinessIdea
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			input: `{"name": "Sock Mates", "slogan": "No sock left behind."}`,
+			want:  BusinessIdea{Name: "Sock Mates", Slogan: "No sock left behind."},
+		},
+		{
+			name:  "trims whitespace",
+			input: "\n{\"name\": \"  Sock Mates \", \"slogan\": \" No sock left behind. \"}\n",
+			want:  BusinessIdea{Name: "Sock Mates", Slogan: "No sock left behind."},
+		},
+		{name: "legacy text format", input: "Name: Sock Mates Slogan: No sock left behind.", wantErr: true},
+		{name: "missing slogan", input: `{"name": "Sock Mates"}`, wantErr: true},
+		{name: "markdown bold", input: `{"name": "**Sock Mates**", "slogan": "No sock left behind."}`, wantErr: true},
+		{name: "embedded label", input: `{"name": "Sock Mates", "slogan": "Slogan: No sock left behind."}`, wantErr: true},
+		{name: "multi-line", input: `{"name": "Sock Mates", "slogan": "No sock\nleft behind."}`, wantErr: true},
+		{name: "name too long", input: `{"name": "` + strings.Repeat("a", maxBusinessNameLength+1) + `", "slogan": "Too long."}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBusinessIdea(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// briefRecordingGenerator records the briefs passed to GenerateImagePrompt
+type briefRecordingGenerator struct {
+	MockGenerator
+	mu     sync.Mutex
+	briefs []*DeckBrief
+}
+
+func (g *briefRecordingGenerator) GenerateImagePrompt(ctx context.Context, brief *DeckBrief, opts GenerationOptions) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.briefs = append(g.briefs, brief)
+	return "a test image prompt", nil
+}
+
+func TestGenerateGameContentCoherentDeck(t *testing.T) {
+	for _, coherent := range []bool{true, false} {
+		generator := &briefRecordingGenerator{}
+		app := &App{generator: generator, coherentDeck: coherent, images: NewMemoryImageStore()}
+
+		if _, err := app.generateGameContent(context.Background(), nil); err != nil {
+			t.Fatalf("coherent=%t: unexpected error: %v", coherent, err)
+		}
+		if len(generator.briefs) != 2 {
+			t.Fatalf("coherent=%t: expected 2 image prompts, got %d", coherent, len(generator.briefs))
+		}
+
+		roles := map[SlideRole]bool{}
+		for _, brief := range generator.briefs {
+			if !coherent {
+				if brief != nil {
+					t.Errorf("expected random scenes without a brief, got %+v", brief)
+				}
+				continue
+			}
+			if brief == nil || brief.BusinessName != "Test Business" || brief.Slogan != "Test Slogan" {
+				t.Fatalf("expected a brief for the business idea, got %+v", brief)
+			}
+			roles[brief.Role] = true
+		}
+		if coherent && (!roles[SlideRoleProblem] || !roles[SlideRoleProduct]) {
+			t.Errorf("expected one problem and one product image, got %v", roles)
+		}
+	}
+}
+
+// slowGenerator takes a fixed time per call and can fail image generation
+type slowGenerator struct {
+	delay     time.Duration
+	imageErr  error
+	cancelled chan struct{}
+}
+
+func (g *slowGenerator) wait(ctx context.Context) error {
+	select {
+	case <-time.After(g.delay):
+		return nil
+	case <-ctx.Done():
+		if g.cancelled != nil {
+			g.cancelled <- struct{}{}
+		}
+		return ctx.Err()
+	}
+}
+
+func (g *slowGenerator) GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error) {
+	return "Test Business", "Test Slogan", g.wait(ctx)
+}
+
+func (g *slowGenerator) GenerateImagePrompt(ctx context.Context, brief *DeckBrief, opts GenerationOptions) (string, error) {
+	if g.imageErr != nil {
+		return "a test image prompt", nil // Fail fast in the image stage
+	}
+	return "a test image prompt", g.wait(ctx)
+}
+
+func (g *slowGenerator) GenerateImage(ctx context.Context, prompt string) (*GeneratedImage, error) {
+	if g.imageErr != nil {
+		return nil, g.imageErr
+	}
+	return &GeneratedImage{Data: []byte("test"), MIMEType: "image/png"}, g.wait(ctx)
+}
+
+func TestGenerateGameContentRunsStagesConcurrently(t *testing.T) {
+	delay := 50 * time.Millisecond
+	app := &App{generator: &slowGenerator{delay: delay}, coherentDeck: true, images: NewMemoryImageStore()}
+
+	start := time.Now()
+	content, err := app.generateGameContent(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	// Idea, then two prompt-then-image chains side by side: three calls deep
+	// instead of five in sequence
+	if elapsed >= 5*delay {
+		t.Errorf("expected stages to overlap, took %v", elapsed)
+	}
+	if content.BusinessName != "Test Business" || content.Image1 == "" || content.Image2 == "" || content.ClappingGif == "" {
+		t.Errorf("incomplete content: %+v", content)
+	}
+}
+
+func TestGenerateGameContentCancelsOnFailure(t *testing.T) {
+	imageErr := errors.New("imagen unavailable")
+	generator := &slowGenerator{delay: time.Second, imageErr: imageErr, cancelled: make(chan struct{}, 4)}
+	// Random scenes start immediately, so the failing image races the slow business idea
+	app := &App{generator: generator, coherentDeck: false, images: NewMemoryImageStore()}
+
+	start := time.Now()
+	_, err := app.generateGameContent(context.Background(), nil)
+	if !errors.Is(err, imageErr) {
+		t.Fatalf("expected the image error, got %v", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellations of sibling stages to be dropped, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("expected sibling stages to be cancelled, took %v", elapsed)
+	}
+	select {
+	case <-generator.cancelled:
+	default:
+		t.Error("expected the business idea stage to observe cancellation")
+	}
+}
+
+func TestRefillWorkers(t *testing.T) {
+	tests := []struct {
+		deficit, target, maxWorkers, want int
+	}{
+		{deficit: 0, target: 16, maxWorkers: 4, want: 0},
+		{deficit: 1, target: 16, maxWorkers: 4, want: 1},
+		{deficit: 5, target: 16, maxWorkers: 4, want: 2},
+		{deficit: 16, target: 16, maxWorkers: 4, want: 4},
+		{deficit: 2, target: 2, maxWorkers: 4, want: 2},
+		{deficit: 3, target: 16, maxWorkers: 0, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := refillWorkers(tt.deficit, tt.target, tt.maxWorkers); got != tt.want {
+			t.Errorf("refillWorkers(%d, %d, %d) = %d, want %d", tt.deficit, tt.target, tt.maxWorkers, got, tt.want)
+		}
+	}
+}
+
+func TestRefillCacheRespectsConcurrencyCeiling(t *testing.T) {
+	generator := &concurrencyTrackingGenerator{}
+	app := &App{
+		generator:      generator,
+		contentCache:   NewContentCache(10),
+		images:         NewMemoryImageStore(),
+		preloadWorkers: 5,
+	}
+	app.scheduler = NewGenerationScheduler(2, app.contentCache, app.generateGameContent)
+
+	app.refillCache(context.Background(), 8, 0)
+
+	if size := app.contentCache.Size(); size != 8 {
+		t.Errorf("expected cache to be refilled to 8, got %d", size)
+	}
+	if generator.peak > 2 {
+		t.Errorf("expected at most 2 concurrent generations, saw %d", generator.peak)
+	}
+	if generator.peak < 2 {
+		t.Errorf("expected refill to use the available concurrency, saw %d", generator.peak)
+	}
+}
+
+// concurrencyTrackingGenerator records how many decks were being generated at once
+type concurrencyTrackingGenerator struct {
+	MockGenerator
+	mu         sync.Mutex
+	inProgress int
+	peak       int
+}
+
+func (g *concurrencyTrackingGenerator) GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error) {
+	g.mu.Lock()
+	g.inProgress++
+	g.peak = max(g.peak, g.inProgress)
+	g.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	g.mu.Lock()
+	g.inProgress--
+	g.mu.Unlock()
+	return "Test Business", "Test Slogan", nil
+}
+
+func TestComputePreloadPlan(t *testing.T) {
+	tests := []struct {
+		name         string
+		popsInWindow int
+		idleFor      time.Duration
+		want         PreloadPlan
+	}{
+		{name: "quiet", popsInWindow: 0, idleFor: 5 * time.Minute, want: PreloadPlan{Target: minPreloadTarget, Interval: refillInterval}},
+		{name: "steady", popsInWindow: 3, idleFor: time.Minute, want: PreloadPlan{Target: 2, Interval: refillInterval, Rate: 0.2}},
+		{name: "lightning round", popsInWindow: 15, idleFor: 0, want: PreloadPlan{Target: 10, Interval: busyRefillInterval, Rate: 1}},
+		{name: "capped at 80%", popsInWindow: 45, idleFor: 0, want: PreloadPlan{Target: 16, Interval: busyRefillInterval, Rate: 3}},
+		{name: "idle", popsInWindow: 0, idleFor: time.Hour, want: PreloadPlan{Target: idlePreloadTarget, Interval: idleRefillInterval, Idle: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computePreloadPlan(20, tt.popsInWindow, tt.idleFor, 30*time.Minute); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanPreloadTracksPops(t *testing.T) {
+	app := &App{contentCache: NewContentCache(20), preloadIdleTimeout: 30 * time.Minute}
+	for i := 0; i < 20; i++ {
+		app.contentCache.Push(GameContent{BusinessName: "Deck"})
+	}
+	startedAt := time.Now().Add(-2 * time.Hour)
+
+	if plan := app.planPreload(startedAt, time.Now()); !plan.Idle {
+		t.Errorf("expected an idle plan before any consumption, got %+v", plan)
+	}
+
+	for i := 0; i < 15; i++ {
+		app.contentCache.Pop()
+	}
+	plan := app.planPreload(startedAt, time.Now())
+	if plan.Idle || plan.Target != 10 || plan.Interval != busyRefillInterval {
+		t.Errorf("expected a busy plan after a burst of pops, got %+v", plan)
+	}
+}
+
+func TestRerollSlide(t *testing.T) {
+	generator := &briefRecordingGenerator{}
+	app := &App{
+		generator:    generator,
+		coherentDeck: true,
+		images:       NewMemoryImageStore(),
+		sessions:     NewSessionManager(),
+		events:       NewEventBroker(),
+	}
+	original := GameContent{BusinessName: "Sock Mates", Slogan: "No sock left behind.", Image1: "old1", Image2: "old2", ClappingGif: "gif"}
+	session, err := app.sessions.GetOrCreate(context.Background(), "Alice", func(ctx context.Context) (*DeckBuild, error) {
+		return completedDeck(original), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := app.events.Subscribe()
+	defer unsubscribe()
+
+	if err := app.rerollSlide(context.Background(), session.ID, SlideImage2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(generator.briefs) != 1 || generator.briefs[0].Role != SlideRoleProduct || generator.briefs[0].BusinessName != "Sock Mates" {
+		t.Errorf("expected a product brief for the current pitch, got %+v", generator.briefs)
+	}
+	updated, _ := app.sessions.Get(session.ID)
+	if updated.Content.Image2 == "old2" || updated.Content.Image2 == "" {
+		t.Errorf("expected a new second image, got %q", updated.Content.Image2)
+	}
+	if updated.Content.Image1 != "old1" || updated.Content.BusinessName != "Sock Mates" {
+		t.Errorf("expected the rest of the deck to be kept, got %+v", updated.Content)
+	}
+	if state, _ := updated.Deck().State(); state.Content != updated.Content {
+		t.Errorf("expected the deck to follow the reroll, got %+v", state.Content)
+	}
+
+	for {
+		select {
+		case event := <-events:
+			if event.Type != "slide" {
+				continue
+			}
+			part := event.Data.(deckStreamEvent)
+			if part.Type != SlideImage2 || part.SessionID != session.ID || part.URL != imageURL(updated.Content.Image2, VariantDisplay) {
+				t.Errorf("unexpected slide event %+v", part)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatal("expected a slide event")
+		}
+	}
+}
+
+func TestRepairDeck(t *testing.T) {
+	app := &App{
+		generator: &MockGenerator{},
+		images:    NewMemoryImageStore(),
+		sessions:  NewSessionManager(),
+		events:    NewEventBroker(),
+		scheduler: NewGenerationScheduler(1, nil, nil),
+	}
+	failed := make(chan string, 1)
+	app.sessions.OnChange(func(action string, session GameSession) {
+		if action == "failed" {
+			failed <- session.ID
+		}
+	})
+	deck := newDeckBuild()
+	session, err := app.sessions.GetOrCreate(context.Background(), "Alice", func(ctx context.Context) (*DeckBuild, error) {
+		return deck, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The talk has started when the second image fails
+	partial := GameContent{BusinessName: "Sock Mates", Slogan: "No sock left behind.", Image1: "img1", ClappingGif: "gif"}
+	deck.finish(&partial, errors.New("image generation failed"))
+	select {
+	case id := <-failed:
+		if id != session.ID {
+			t.Fatalf("expected session %s to be reported, got %s", session.ID, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the failed deck to be reported")
+	}
+
+	app.repairDeck(session.ID)
+	repaired, _ := app.sessions.Get(session.ID)
+	if repaired.Content.Image2 == "" || repaired.Content.Image1 != "img1" {
+		t.Errorf("expected only the missing image to be generated, got %+v", repaired.Content)
+	}
+	if state, _ := repaired.Deck().State(); !state.Done || state.Err != nil {
+		t.Errorf("expected the repaired deck to be whole, got %+v", state)
+	}
+}
+
+// themeRecordingGenerator records the event theme each call runs under
+type themeRecordingGenerator struct {
+	MockGenerator
+	mu     sync.Mutex
+	themes []string
+}
+
+func (g *themeRecordingGenerator) record(opts GenerationOptions) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.themes = append(g.themes, opts.Theme.String())
+}
+
+func (g *themeRecordingGenerator) GenerateBusinessIdea(ctx context.Context, opts GenerationOptions) (string, string, error) {
+	g.record(opts)
+	return g.MockGenerator.GenerateBusinessIdea(ctx, opts)
+}
+
+func (g *themeRecordingGenerator) GenerateImagePrompt(ctx context.Context, brief *DeckBrief, opts GenerationOptions) (string, error) {
+	g.record(opts)
+	return g.MockGenerator.GenerateImagePrompt(ctx, brief, opts)
+}
+
+func TestGenerateGameContentUsesTheme(t *testing.T) {
+	generator := &themeRecordingGenerator{}
+	app := &App{generator: generator, coherentDeck: true, images: NewMemoryImageStore()}
+	app.theme = Theme{Text: "Space week", Keywords: []string{"neon"}}
+
+	content, err := app.generateGameContent(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.Theme != "Space week (neon)" {
+		t.Errorf("expected the deck to record its theme, got %q", content.Theme)
+	}
+	if len(generator.themes) != 3 {
+		t.Fatalf("expected an idea and two prompts, got %d calls", len(generator.themes))
+	}
+	for _, theme := range generator.themes {
+		if theme != "Space week (neon)" {
+			t.Errorf("expected every request to carry the theme, got %q", theme)
+		}
+	}
+}
+
+func TestBusinessIdeaRequestUsesKeywordOnlyTheme(t *testing.T) {
+	generator := &AiGenerator{}
+	opts := GenerationOptions{Theme: Theme{Keywords: []string{"neon", "retro"}}}
+
+	request := generator.businessIdeaRequest(opts)
+	if len(request.StyleKeywords) != 2 || request.StyleKeywords[0] != "neon" {
+		t.Errorf("expected the style keywords to be sent, got %+v", request.StyleKeywords)
+	}
+	if !strings.Contains(request.Instructions, "event theme") {
+		t.Errorf("expected the instructions to ask for the theme, got %q", request.Instructions)
+	}
+
+	if request := generator.businessIdeaRequest(GenerationOptions{}); request.Theme != "" || request.StyleKeywords != nil {
+		t.Errorf("expected no theme without one set, got %+v", request)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	gameDuration  = 60 * time.Second // Length of one talk
+	slideInterval = 15 * time.Second // Time spent on each slide before auto-advancing
+	slideCount    = 5                // Welcome, pitch, image 1, image 2 and the closing slide
+
+	// sessionCreateTimeout bounds how long a session's deck is waited for once
+	// every screen asking for it may have given up
+	sessionCreateTimeout = 5 * time.Minute
+)
+
+// ErrSessionNotFound is returned when a session ID does not match an active session
+var ErrSessionNotFound = errors.New("game session not found")
+
+// GameSession binds one participant's game to a single deck of content, so
+// refreshing the page or opening a second screen shows the same slides
+type GameSession struct {
+	ID              string
+	ParticipantName string
+	Content         GameContent // Parts of the deck generated so far
+	CreatedAt       time.Time
+
+	deck *DeckBuild // The deck being followed, which may still be generating
+
+	// The game clock accumulates into elapsed while paused and runs from
+	// runningSince otherwise
+	elapsed      time.Duration
+	runningSince time.Time
+}
+
+// GameTimerState is the authoritative timer state the game page renders
+type GameTimerState struct {
+	SessionID    string `json:"sessionId"`
+	Paused       bool   `json:"paused"`
+	Finished     bool   `json:"finished"`
+	ElapsedMs    int64  `json:"elapsedMs"`
+	RemainingMs  int64  `json:"remainingMs"`
+	CurrentSlide int    `json:"currentSlide"`
+}
+
+// Paused reports whether the game clock is stopped
+func (s GameSession) Paused() bool {
+	return s.runningSince.IsZero()
+}
+
+// Elapsed returns how much of the game has been played, capped at the game duration
+func (s GameSession) Elapsed(now time.Time) time.Duration {
+	elapsed := s.elapsed
+	if !s.Paused() {
+		elapsed += now.Sub(s.runningSince)
+	}
+	return min(elapsed, gameDuration)
+}
+
+// TimerState returns the session's timer state at the given time
+func (s GameSession) TimerState(now time.Time) GameTimerState {
+	elapsed := s.Elapsed(now)
+	return GameTimerState{
+		SessionID:    s.ID,
+		Paused:       s.Paused(),
+		Finished:     elapsed >= gameDuration,
+		ElapsedMs:    elapsed.Milliseconds(),
+		RemainingMs:  (gameDuration - elapsed).Milliseconds(),
+		CurrentSlide: min(int(elapsed/slideInterval), slideCount-1),
+	}
+}
+
+// Deck returns the deck the session is showing
+func (s GameSession) Deck() *DeckBuild {
+	if s.deck == nil {
+		return completedDeck(s.Content)
+	}
+	return s.deck
+}
+
+// SessionManager tracks the active game session for each participant
+type SessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*GameSession // Active sessions by ID
+	active   map[string]string       // Participant name to active session ID
+	pending  map[string]*sessionCall // Sessions currently being created, by participant
+
+	// onChange is called after a session starts, changes or finishes
+	onChange func(action string, session GameSession)
+}
+
+// sessionCall lets concurrent requests for the same participant share one creation
+type sessionCall struct {
+	done    chan struct{}
+	session GameSession
+	err     error
+}
+
+// NewSessionManager creates an empty session manager
+func NewSessionManager() *SessionManager {
+	return &SessionManager{
+		sessions: make(map[string]*GameSession),
+		active:   make(map[string]string),
+		pending:  make(map[string]*sessionCall),
+	}
+}
+
+// GetOrCreate returns the participant's active session, creating one with the
+// deck from create if there is none. Concurrent callers for the same
+// participant wait for a single creation rather than each consuming a deck.
+// The creation is not tied to any one caller's ctx, so a screen that gives up
+// does not fail the others; each caller only stops waiting when its own ctx
+// is done. The deck may still be generating; the session fills in its
+// remaining parts as they arrive.
+func (sm *SessionManager) GetOrCreate(ctx context.Context, participantName string, create func(ctx context.Context) (*DeckBuild, error)) (GameSession, error) {
+	sm.mu.Lock()
+	if id, ok := sm.active[participantName]; ok {
+		session := *sm.sessions[id]
+		sm.mu.Unlock()
+		return session, nil
+	}
+	call, ok := sm.pending[participantName]
+	if !ok {
+		call = &sessionCall{done: make(chan struct{})}
+		sm.pending[participantName] = call
+		go sm.create(context.WithoutCancel(ctx), participantName, call, create)
+	}
+	sm.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.session, call.err
+	case <-ctx.Done():
+		return GameSession{}, ctx.Err()
+	}
+}
+
+// create builds the participant's session for call and wakes its waiters
+func (sm *SessionManager) create(ctx context.Context, participantName string, call *sessionCall, create func(ctx context.Context) (*DeckBuild, error)) {
+	ctx, cancel := context.WithTimeout(ctx, sessionCreateTimeout)
+	deck, err := create(ctx)
+	cancel()
+
+	sm.mu.Lock()
+	delete(sm.pending, participantName)
+	if err != nil {
+		call.err = err
+	} else {
+		state, _ := deck.State()
+		session := &GameSession{
+			ID:              newID(),
+			ParticipantName: participantName,
+			Content:         state.Content,
+			CreatedAt:       time.Now(),
+			deck:            deck,
+		}
+		// The clock starts as soon as the pitch is ready
+		session.runningSince = session.CreatedAt
+		sm.sessions[session.ID] = session
+		sm.active[participantName] = session.ID
+		call.session = *session
+	}
+	sm.mu.Unlock()
+	close(call.done)
+
+	if call.err == nil {
+		sm.changed("started", call.session)
+		sm.follow(call.session.ID, deck, call.session.Content)
+	}
+}
+
+// follow copies parts of the deck into the session as they are generated,
+// until the deck is done or the session finishes. A deck that fails after
+// the talk started is reported as "failed".
+func (sm *SessionManager) follow(id string, deck *DeckBuild, content GameContent) {
+	for {
+		state, changed := deck.State()
+		if state.Content != content {
+			content = state.Content
+			if _, err := sm.update(id, "updated", func(s *GameSession, now time.Time) {
+				s.Content = content
+			}); err != nil {
+				return
+			}
+		}
+		if state.Done {
+			if session, ok := sm.Get(id); ok && state.Err != nil {
+				sm.changed("failed", session)
+			}
+			return
+		}
+		<-changed
+	}
+}
+
+// OnChange registers a function called after a session starts, changes or
+// finishes. The action names what happened.
+func (sm *SessionManager) OnChange(fn func(action string, session GameSession)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.onChange = fn
+}
+
+// changed calls the change hook. It must be called without sm.mu held.
+func (sm *SessionManager) changed(action string, session GameSession) {
+	sm.mu.Lock()
+	fn := sm.onChange
+	sm.mu.Unlock()
+	if fn != nil {
+		fn(action, session)
+	}
+}
+
+// Get returns the active session with the given ID
+func (sm *SessionManager) Get(id string) (GameSession, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[id]
+	if !ok {
+		return GameSession{}, false
+	}
+	return *session, true
+}
+
+// List returns all active sessions, oldest first
+func (sm *SessionManager) List() []GameSession {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sessions := make([]GameSession, 0, len(sm.sessions))
+	for _, session := range sm.sessions {
+		sessions = append(sessions, *session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+// Pause stops the session's game clock
+func (sm *SessionManager) Pause(id string) (GameSession, error) {
+	return sm.update(id, "paused", func(s *GameSession, now time.Time) {
+		if !s.Paused() {
+			s.elapsed = s.Elapsed(now)
+			s.runningSince = time.Time{}
+		}
+	})
+}
+
+// Resume restarts the session's game clock from where it was paused
+func (sm *SessionManager) Resume(id string) (GameSession, error) {
+	return sm.update(id, "resumed", func(s *GameSession, now time.Time) {
+		if s.Paused() {
+			s.runningSince = now
+		}
+	})
+}
+
+// Restart rewinds the session to the first slide with a full clock
+func (sm *SessionManager) Restart(id string) (GameSession, error) {
+	return sm.update(id, "restarted", func(s *GameSession, now time.Time) {
+		s.elapsed = 0
+		s.runningSince = now
+	})
+}
+
+// SkipToSlide moves the session to the start of the given slide, keeping the
+// clock paused or running as it was
+func (sm *SessionManager) SkipToSlide(id string, slide int) (GameSession, error) {
+	if slide < 0 || slide >= slideCount {
+		return GameSession{}, fmt.Errorf("slide %d out of range", slide)
+	}
+	return sm.update(id, "skipped", func(s *GameSession, now time.Time) {
+		s.elapsed = time.Duration(slide) * slideInterval
+		if !s.Paused() {
+			s.runningSince = now
+		}
+	})
+}
+
+// ReplaceContent applies fn to the session's content once its deck is done,
+// and to the deck itself so a reloaded page shows the change too
+func (sm *SessionManager) ReplaceContent(id string, fn func(content *GameContent)) (GameSession, error) {
+	return sm.update(id, "rerolled", func(s *GameSession, now time.Time) {
+		fn(&s.Content)
+		if s.deck != nil {
+			s.deck.update(s.Content)
+		}
+	})
+}
+
+// update applies fn to the active session with the given ID and reports the
+// change as action
+func (sm *SessionManager) update(id, action string, fn func(s *GameSession, now time.Time)) (GameSession, error) {
+	sm.mu.Lock()
+	session, ok := sm.sessions[id]
+	if !ok {
+		sm.mu.Unlock()
+		return GameSession{}, ErrSessionNotFound
+	}
+	fn(session, time.Now())
+	updated := *session
+	sm.mu.Unlock()
+
+	sm.changed(action, updated)
+	return updated, nil
+}
+
+// Finish ends the participant's active session, if any, so their next game
+// starts with a fresh deck
+func (sm *SessionManager) Finish(participantName string) {
+	sm.mu.Lock()
+	id, ok := sm.active[participantName]
+	if !ok {
+		sm.mu.Unlock()
+		return
+	}
+	session := *sm.sessions[id]
+	delete(sm.active, participantName)
+	delete(sm.sessions, id)
+	sm.mu.Unlock()
+
+	sm.changed("finished", session)
+}
+
+// newID returns a random hex identifier
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetOrCreateOutlivesFirstCaller(t *testing.T) {
+	sessions := NewSessionManager()
+	release := make(chan struct{})
+	create := func(ctx context.Context) (*DeckBuild, error) {
+		select {
+		case <-release:
+			return completedDeck(GameContent{BusinessName: "Test Business"}), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// The first screen starts the creation, then is closed
+	firstCtx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := sessions.GetOrCreate(firstCtx, "Alice", create)
+		first <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	second := make(chan GameSession, 1)
+	go func() {
+		session, err := sessions.GetOrCreate(context.Background(), "Alice", create)
+		if err != nil {
+			t.Errorf("second caller failed: %v", err)
+		}
+		second <- session
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the first caller to stop waiting, got %v", err)
+	}
+
+	close(release)
+	select {
+	case session := <-second:
+		if session.Content.BusinessName != "Test Business" {
+			t.Errorf("expected the second caller to get the deck, got %+v", session.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second caller never got its session")
+	}
+}
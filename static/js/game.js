@@ -5,9 +5,9 @@ document.addEventListener('DOMContentLoaded', () => {
     const slideContainer = document.getElementById('slide-container');
     const nextParticipantForm = document.getElementById('next-participant-form');
     let currentSlide = 0;
-    let timeLeft = 60;
 
     const participantName = window.location.pathname.split('/').pop();
+    const source = new EventSource('/events');
 
     // Show more informative loading message
     const loadingMessages = [
@@ -26,54 +26,174 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     }, 3000);
 
-    fetch(`/api/game-data/${participantName}`)
-        .then(response => response.json())
-        .then(data => {
+    // Once the server says what the deck is waiting on, show that instead
+    source.addEventListener('progress', (e) => {
+        const progress = JSON.parse(e.data);
+        if (progress.participantName === decodeURIComponent(participantName)) {
             clearInterval(messageInterval);
-            
-            document.getElementById('business-name').textContent = data.businessName;
-            document.getElementById('slogan').textContent = data.slogan;
-            document.getElementById('image1').src = data.image1;
-            document.getElementById('image2').src = data.image2;
-            document.getElementById('clapping-gif').src = data.clappingGif;
-
-            loader.style.display = 'none';
-            slideContainer.style.display = 'block';
-            slides[0].style.display = 'flex';
-
-            startTimer();
+            loader.querySelector('p').textContent = progress.message;
+        }
+    });
+
+    // Slides fill in as the server streams them, so the talk can start on the
+    // pitch while the images are still rendering
+    let sessionId = null;
+    let started = false;
+    const setImage = (id, url) => {
+        document.getElementById(id).src = url;
+        const pending = document.getElementById(`${id}-pending`);
+        if (pending) {
+            pending.style.display = 'none';
+        }
+    };
+
+    const applyPart = (part) => {
+        switch (part.type) {
+            case 'session':
+                sessionId = part.sessionId;
+                break;
+            case 'pitch':
+                document.getElementById('business-name').textContent = part.businessName;
+                document.getElementById('slogan').textContent = part.slogan;
+                if (started) {
+                    break; // A rerolled pitch mid-talk
+                }
+                started = true;
+                clearInterval(messageInterval);
+
+                loader.style.display = 'none';
+                slideContainer.style.display = 'block';
+                showSlide(0);
+
+                startTimer(sessionId);
+                break;
+            case 'image1':
+            case 'image2':
+            case 'clappingGif':
+                setImage(part.type === 'clappingGif' ? 'clapping-gif' : part.type, part.url);
+                break;
+            case 'error':
+                document.querySelectorAll('.image-pending').forEach(pending => {
+                    pending.textContent = 'Regenerating this slide...';
+                });
+                break;
+            case 'failed':
+                document.querySelectorAll('.image-pending').forEach(pending => {
+                    pending.textContent = 'This slide could not be generated. Improvise!';
+                });
+                break;
+        }
+    };
+
+    // The host can regenerate a single slide mid-talk
+    source.addEventListener('slide', (e) => {
+        const part = JSON.parse(e.data);
+        if (sessionId && part.sessionId === sessionId) {
+            applyPart(part);
+        }
+    });
+
+    fetch(`/api/game-stream/${participantName}`)
+        .then(response => {
+            if (!response.ok || !response.body) {
+                throw new Error(`game stream request failed with status ${response.status}`);
+            }
+            const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+            let buffered = '';
+            const pump = () => reader.read().then(({ value, done }) => {
+                if (done) {
+                    return;
+                }
+                buffered += value;
+                const lines = buffered.split('\n');
+                buffered = lines.pop();
+                lines.filter(line => line.trim()).forEach(line => applyPart(JSON.parse(line)));
+                return pump();
+            });
+            return pump();
         })
         .catch(error => {
             clearInterval(messageInterval);
-            console.error('Error fetching game data:', error);
-            loader.innerHTML = '<p>Failed to load game data. Please try again.</p>';
+            console.error('Error streaming game data:', error);
+            if (!sessionId || loader.style.display !== 'none') {
+                loader.innerHTML = '<p>Failed to load game data. Please try again.</p>';
+            }
         });
 
 
-    const advanceSlide = () => {
-        if (currentSlide < slides.length - 1) {
-            slides[currentSlide].style.display = 'none';
-            currentSlide++;
-            slides[currentSlide].style.display = 'flex';
+    const showSlide = (index) => {
+        if (index === currentSlide && slides[index].style.display === 'flex') {
+            return;
         }
+        slides[currentSlide].style.display = 'none';
+        currentSlide = index;
+        slides[currentSlide].style.display = 'flex';
     };
 
-    const startTimer = () => {
-        const timerInterval = setInterval(() => {
-            timeLeft--;
-            const minutes = Math.floor(timeLeft / 60);
-            const seconds = timeLeft % 60;
-            timerDisplay.textContent = `${minutes}:${seconds.toString().padStart(2, '0')}`;
+    const formatTime = (ms) => {
+        const totalSeconds = Math.ceil(Math.max(ms, 0) / 1000);
+        const minutes = Math.floor(totalSeconds / 60);
+        const seconds = totalSeconds % 60;
+        return `${minutes}:${seconds.toString().padStart(2, '0')}`;
+    };
 
-            if (timeLeft % 15 === 0) {
-                advanceSlide();
-            }
+    // The server owns the game clock; we sync with it and interpolate between
+    // updates so the countdown stays smooth.
+    let timerState = null;
+    let polledAt = 0;
+
+    const render = () => {
+        if (!timerState) {
+            return;
+        }
+
+        let remaining = timerState.remainingMs;
+        if (!timerState.paused && !timerState.finished) {
+            remaining -= Date.now() - polledAt;
+        }
+
+        showSlide(timerState.currentSlide);
+
+        if (timerState.finished || remaining <= 0) {
+            timerDisplay.textContent = "Time's Up!";
+            nextParticipantForm.style.display = 'block';
+            return;
+        }
+
+        nextParticipantForm.style.display = 'none';
+        timerDisplay.textContent = timerState.paused ? `${formatTime(remaining)} (paused)` : formatTime(remaining);
+    };
 
-            if (timeLeft <= 0) {
-                clearInterval(timerInterval);
-                timerDisplay.textContent = "Time's Up!";
-                nextParticipantForm.style.display = 'block';
+    const pollState = (sessionId) => {
+        return fetch(`/api/game-state/${sessionId}`)
+            .then(response => {
+                if (!response.ok) {
+                    throw new Error(`game state request failed with status ${response.status}`);
+                }
+                return response.json();
+            })
+            .then(state => {
+                timerState = state;
+                polledAt = Date.now();
+                render();
+            })
+            .catch(error => console.error('Error fetching game state:', error));
+    };
+
+    const startTimer = (sessionId) => {
+        pollState(sessionId);
+        // Host actions arrive instantly over the event stream; the slow poll
+        // just keeps us honest if the stream drops.
+        source.addEventListener('game', (e) => {
+            const game = JSON.parse(e.data);
+            const ours = game.games.find(g => g.sessionId === sessionId);
+            if (ours) {
+                timerState = ours.timer;
+                polledAt = Date.now();
+                render();
             }
-        }, 1000);
-    }
-}); 
\ No newline at end of file
+        });
+        setInterval(() => pollState(sessionId), 5000);
+        setInterval(render, 250);
+    };
+});
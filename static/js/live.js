@@ -0,0 +1,149 @@
+// Live updates for the index and admin pages, driven by the server's event stream.
+// Each renderer only runs if the page has the element it updates.
+document.addEventListener('DOMContentLoaded', () => {
+    const el = (tag, attrs = {}, children = []) => {
+        const node = document.createElement(tag);
+        Object.entries(attrs).forEach(([key, value]) => {
+            if (key === 'text') {
+                node.textContent = value;
+            } else {
+                node.setAttribute(key, value);
+            }
+        });
+        children.forEach(child => node.appendChild(child));
+        return node;
+    };
+
+    const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+
+    const postForm = (action, fields, button) => {
+        const inputs = Object.entries({ ...fields, csrf_token: csrfToken })
+            .map(([name, value]) => el('input', { type: 'hidden', name, value }));
+        return el('form', { action, method: 'post', style: 'display: inline;' }, [...inputs, button]);
+    };
+
+    const renderIndexQueue = (queue) => {
+        const nextUp = document.getElementById('next-up');
+        if (nextUp) {
+            nextUp.replaceChildren(queue.next
+                ? el('div', { class: 'next-up-section' }, [
+                    el('h2', { text: 'Next Up: ' }, [el('span', { class: 'next-participant', text: queue.next })]),
+                    el('a', { href: `/game/${encodeURIComponent(queue.next)}`, class: 'start-game-btn', text: 'Start Game' }),
+                ])
+                : el('div', { class: 'no-participants' }, [
+                    el('h2', { text: 'The stage is empty!' }),
+                    el('p', { text: 'Go to the admin panel to add participants to the queue.' }),
+                ]));
+        }
+
+        const waiting = document.getElementById('waiting-list');
+        if (waiting) {
+            waiting.replaceChildren(...queue.participants.slice(1).map(name => el('li', { text: name })));
+        }
+    };
+
+    const renderAdminQueue = (queue) => {
+        const list = document.getElementById('queue-list');
+        if (list) {
+            const items = queue.participants.map(name => el('li', {}, [
+                el('span', { text: name }),
+                postForm('/remove-participant', { name }, el('button', { type: 'submit', class: 'remove-btn', text: 'Remove' })),
+            ]));
+            list.replaceChildren(...(items.length ? items : [el('li', { text: 'No participants in the queue.' })]));
+        }
+
+        // Don't clobber the list while the host is editing it
+        const names = document.getElementById('participant-names');
+        if (names && document.activeElement !== names) {
+            names.value = queue.participants.map(name => `${name}\n`).join('');
+        }
+    };
+
+    const formatBytes = (n) => {
+        const units = [['GB', 1 << 30], ['MB', 1 << 20], ['KB', 1 << 10]];
+        const [unit, size] = units.find(([, size]) => n >= size) || ['B', 1];
+        return unit === 'B' ? `${n} B` : `${(n / size).toFixed(1)} ${unit}`;
+    };
+
+    const renderCache = (cache) => {
+        const size = document.getElementById('cache-size');
+        if (size) {
+            size.textContent = `${cache.size} / ${cache.maxSize}`;
+        }
+        const bytes = document.getElementById('cache-bytes');
+        if (bytes) {
+            bytes.textContent = cache.maxBytes
+                ? `${formatBytes(cache.bytes)} of ${formatBytes(cache.maxBytes)}`
+                : formatBytes(cache.bytes);
+        }
+        const stats = document.getElementById('cache-stats');
+        if (stats) {
+            stats.textContent = `${cache.consumed} served, ${cache.expired} dropped as stale, ${cache.rethemed} dropped for a new theme, ${cache.evicted} evicted`;
+        }
+        const status = document.getElementById('cache-status');
+        if (status) {
+            status.textContent = cache.loaded ? 'Loaded' : 'Loading...';
+        }
+    };
+
+    const gameStatus = (timer) => {
+        if (timer.finished) {
+            return "time's up";
+        }
+        return timer.paused ? 'paused' : 'running';
+    };
+
+    const renderGames = (game) => {
+        const nowPlaying = document.getElementById('now-playing');
+        if (nowPlaying) {
+            nowPlaying.textContent = game.games.length
+                ? `On stage now: ${game.games.map(g => g.participantName).join(', ')}`
+                : '';
+        }
+
+        const list = document.getElementById('games-list');
+        if (!list) {
+            return;
+        }
+        const items = game.games.map(g => {
+            const session = g.sessionId;
+            const toggle = g.timer.paused ? 'resume' : 'pause';
+            const slideSelect = el('select', { name: 'slide' },
+                [0, 1, 2, 3, 4].map(n => el('option', { value: n, text: `Slide ${n}` })));
+            const rerollSelect = el('select', { name: 'slide' },
+                [['pitch', 'Business idea'], ['image1', 'Image 1'], ['image2', 'Image 2'], ['clappingGif', 'Closing GIF']]
+                    .map(([value, text]) => el('option', { value, text })));
+            return el('li', { class: 'game-controls' }, [
+                el('span', {}, [
+                    el('strong', { text: g.participantName }),
+                    document.createTextNode(` — slide ${g.timer.currentSlide}, ${gameStatus(g.timer)}`),
+                ]),
+                el('span', {}, [
+                    postForm('/game-control', { session }, el('button', { type: 'submit', name: 'action', value: toggle, text: toggle === 'pause' ? 'Pause' : 'Resume' })),
+                    postForm('/game-control', { session }, el('button', { type: 'submit', name: 'action', value: 'restart', text: 'Restart' })),
+                    postForm('/game-control', { session, action: 'skip' }, el('span', {}, [slideSelect, el('button', { type: 'submit', text: 'Skip' })])),
+                    postForm('/reroll-slide', { session }, el('span', {}, [rerollSelect, el('button', { type: 'submit', text: 'Reroll' })])),
+                ]),
+            ]);
+        });
+        list.replaceChildren(...(items.length ? items : [el('li', { text: 'No games in progress.' })]));
+    };
+
+    const renderTheme = (theme) => {
+        const line = document.getElementById('event-theme');
+        if (line) {
+            line.querySelector('span').textContent = theme.text || '';
+            line.style.display = theme.text ? '' : 'none';
+        }
+    };
+
+    const source = new EventSource('/events');
+    source.addEventListener('queue', (e) => {
+        const queue = JSON.parse(e.data);
+        renderIndexQueue(queue);
+        renderAdminQueue(queue);
+    });
+    source.addEventListener('cache', (e) => renderCache(JSON.parse(e.data)));
+    source.addEventListener('game', (e) => renderGames(JSON.parse(e.data)));
+    source.addEventListener('theme', (e) => renderTheme(JSON.parse(e.data)));
+});
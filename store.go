@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// State is the event state that must survive a restart
+type State struct {
+	Participants   []string `json:"participants"`
+	ReviewRequired bool     `json:"reviewRequired,omitempty"` // Only host-approved decks are shown
+	Theme          Theme    `json:"theme,omitzero"`
+	Glossary       Glossary `json:"glossary,omitzero"`
+}
+
+// StateStore persists event state between restarts
+type StateStore interface {
+	Load() (*State, error)
+	Save(state *State) error
+}
+
+// FileStateStore keeps state as a JSON document on local disk
+type FileStateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStateStore creates a state store backed by the JSON file at path
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+// Load reads the state file, returning an empty state if it does not exist yet
+func (s *FileStateStore) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode state file %s: %w", s.path, err)
+	}
+	return &state, nil
+}
+
+// Save atomically replaces the state file with the given state
+func (s *FileStateStore) Save(state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op once the rename has succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	// Sync the directory so the rename itself is durable
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// saveStateLocked writes the current event state through to the state store.
+// Assumes participantsMu is already locked.
+func (app *App) saveStateLocked() {
+	if app.stateStore == nil {
+		return
+	}
+
+	state := &State{
+		Participants: append([]string(nil), app.participants...),
+		Theme:        app.currentTheme(),
+		Glossary:     app.currentGlossary(),
+	}
+	if app.contentCache != nil {
+		state.ReviewRequired = app.contentCache.ReviewRequired()
+	}
+	if err := app.stateStore.Save(state); err != nil {
+		log.Printf("Failed to persist event state: %v", err)
+	}
+}
+
+// loadState restores event state from the state store
+func (app *App) loadState() error {
+	if app.stateStore == nil {
+		return nil
+	}
+
+	state, err := app.stateStore.Load()
+	if err != nil {
+		return err
+	}
+
+	app.participantsMu.Lock()
+	defer app.participantsMu.Unlock()
+	app.participants = state.Participants
+	app.themeMu.Lock()
+	app.theme = state.Theme
+	app.themeMu.Unlock()
+	app.glossaryMu.Lock()
+	app.glossary = state.Glossary
+	app.glossaryMu.Unlock()
+	if app.contentCache != nil {
+		app.contentCache.SetReviewRequired(state.ReviewRequired)
+		app.contentCache.SetTheme(state.Theme.String())
+		app.contentCache.SetDoNotMention(state.Glossary.DoNotMention)
+	}
+	return nil
+}
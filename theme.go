@@ -0,0 +1,49 @@
+package main
+
+import "strings"
+
+// maxThemeLength bounds the theme text so it cannot crowd out the prompt
+const maxThemeLength = 200
+
+// Theme steers every deck generated for the event
+type Theme struct {
+	Text     string   `json:"text,omitempty"`     // e.g. "Space week"
+	Keywords []string `json:"keywords,omitempty"` // Optional style keywords for the images
+}
+
+// parseTheme builds a theme from the admin form, where keywords are comma separated
+func parseTheme(text, keywords string) Theme {
+	theme := Theme{Text: strings.TrimSpace(text)}
+	for _, keyword := range strings.Split(keywords, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			theme.Keywords = append(theme.Keywords, keyword)
+		}
+	}
+	return theme
+}
+
+// IsZero reports whether no theme is set
+func (t Theme) IsZero() bool {
+	return t.Text == "" && len(t.Keywords) == 0
+}
+
+// String describes the theme. Decks remember it to tell which theme they
+// were generated under.
+func (t Theme) String() string {
+	if len(t.Keywords) == 0 {
+		return t.Text
+	}
+	return t.Text + " (" + strings.Join(t.Keywords, ", ") + ")"
+}
+
+// KeywordList returns the keywords as the admin form shows them
+func (t Theme) KeywordList() string {
+	return strings.Join(t.Keywords, ", ")
+}
+
+// currentTheme returns the event theme
+func (app *App) currentTheme() Theme {
+	app.themeMu.RLock()
+	defer app.themeMu.RUnlock()
+	return app.theme
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // Imagen returns PNGs
+)
+
+// Image variants served from /images/{name}?variant=
+const (
+	VariantOriginal = "original"
+	VariantDisplay  = "display" // Sized for the projector
+	VariantThumb    = "thumb"   // Sized for the admin page
+)
+
+// imageVariant describes a downscaled JPEG rendition of a stored image
+type imageVariant struct {
+	maxWidth, maxHeight int
+	quality             int
+}
+
+var imageVariants = map[string]imageVariant{
+	VariantDisplay: {maxWidth: 1920, maxHeight: 1080, quality: 85},
+	VariantThumb:   {maxWidth: 320, maxHeight: 180, quality: 75},
+}
+
+// renderVariant decodes an image and re-encodes it as a JPEG that fits within
+// the variant's bounds. Images are never scaled up.
+func renderVariant(data []byte, variant imageVariant) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	// JPEG has no alpha, so flatten onto white
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	width, height := fitWithin(bounds.Dx(), bounds.Dy(), variant.maxWidth, variant.maxHeight)
+	resized := flat
+	if width != bounds.Dx() || height != bounds.Dy() {
+		resized = downscale(flat, width, height)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: variant.quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fitWithin returns the largest size with the same aspect ratio as
+// width x height that fits in maxWidth x maxHeight, without scaling up
+func fitWithin(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(height*maxWidth/width, 1)
+	}
+	return max(width*maxHeight/height, 1), maxHeight
+}
+
+// downscale shrinks src to width x height by averaging the block of source
+// pixels behind each destination pixel
+func downscale(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+
+	for y := 0; y < height; y++ {
+		sy0 := y * srcHeight / height
+		sy1 := max((y+1)*srcHeight/height, sy0+1)
+		for x := 0; x < width; x++ {
+			sx0 := x * srcWidth / width
+			sx1 := max((x+1)*srcWidth/width, sx0+1)
+
+			var r, g, b, a, n int
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}